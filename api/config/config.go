@@ -0,0 +1,190 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds runtime settings loaded from environment variables
+type Config struct {
+	Port string
+
+	// Retention janitor settings
+	JanitorInterval        time.Duration
+	AutoArchiveAfterDays   int // Archive completed videos older than this (0 disables)
+	PurgeArchivedAfterDays int // Remove archived records older than this (0 disables)
+
+	// Accepted upload file extensions, lowercase with a leading dot
+	AllowedVideoTypes []string
+	AllowedImageTypes []string
+	MaxVideoDuration  time.Duration // Longer videos are rejected before processing (0 disables)
+	MaxClipDuration   time.Duration // Longest clip that can be cut from a video (0 disables)
+
+	// Store videos in videos/YYYY/MM/DD subdirectories by upload date
+	PartitionVideosByDate bool
+
+	// Retries of transient face detection failures
+	ProcessingMaxRetries   int
+	ProcessingRetryBackoff time.Duration // Wait before the first retry, doubled after each attempt
+
+	// Face crop settings
+	FaceCropPadding float64 // Fraction of the face size added on each side of saved crops
+	MinFaceArea     int     // Faces with a smaller bounding box area in pixels are ignored (0 disables)
+
+	// Key clients send in X-Access-Key to see restricted videos (empty disables access)
+	RestrictedAccessKey string
+
+	// Face model version recorded with results so outdated ones can be re-analyzed
+	ModelVersion string
+
+	// How face encodings are compared by default: euclidean or cosine
+	DistanceMetric string
+
+	// Uploads a single client IP may have in flight at once (0 disables)
+	MaxUploadsPerClient int
+
+	// Reverse proxies, as IPs or CIDRs, whose X-Forwarded-For header is
+	// trusted for client IPs (empty trusts none and uses the connection address)
+	TrustedProxies []string
+
+	// Requests to the face search endpoint are aborted after this long (0 disables)
+	SearchTimeout time.Duration
+
+	// Videos compared with a search image at the same time
+	SearchConcurrency int
+
+	// Write the JSON storage files without indentation, which is faster for large datasets
+	CompactStorageJSON bool
+
+	// Response compression settings
+	GzipEnabled bool
+	GzipMinSize int // Responses smaller than this many bytes are not compressed
+
+	// Prefix of links in responses, e.g. https://example.com/trinetra (empty keeps them relative)
+	PublicBaseURL string
+}
+
+// Load reads the configuration from the environment, applying defaults
+func Load() *Config {
+	return &Config{
+		Port:                   getEnv("PORT", "8080"),
+		JanitorInterval:        time.Duration(getEnvInt("JANITOR_INTERVAL_MINUTES", 60)) * time.Minute,
+		AutoArchiveAfterDays:   getEnvInt("AUTO_ARCHIVE_DAYS", 0),
+		PurgeArchivedAfterDays: getEnvInt("PURGE_ARCHIVED_DAYS", 0),
+		AllowedVideoTypes:      getEnvExtensions("ALLOWED_VIDEO_TYPES", ".mp4,.avi,.mov,.mkv,.wmv,.flv,.webm"),
+		AllowedImageTypes:      getEnvExtensions("ALLOWED_IMAGE_TYPES", ".jpg,.jpeg,.png,.bmp,.gif"),
+		MaxVideoDuration:       time.Duration(getEnvInt("MAX_VIDEO_DURATION_SECONDS", 0)) * time.Second,
+		MaxClipDuration:        time.Duration(getEnvInt("MAX_CLIP_SECONDS", 300)) * time.Second,
+		PartitionVideosByDate:  getEnvBool("PARTITION_VIDEOS_BY_DATE", false),
+		ProcessingMaxRetries:   getEnvInt("PROCESSING_MAX_RETRIES", 2),
+		ProcessingRetryBackoff: time.Duration(getEnvInt("PROCESSING_RETRY_BACKOFF_SECONDS", 2)) * time.Second,
+		FaceCropPadding:        getEnvFloat("FACE_CROP_PADDING", 0),
+		MinFaceArea:            getEnvInt("MIN_FACE_AREA", 0),
+		ModelVersion:           getEnv("MODEL_VERSION", "dlib_face_recognition_resnet_model_v1"),
+		DistanceMetric:         getEnv("FACE_DISTANCE_METRIC", "euclidean"),
+		RestrictedAccessKey:    getEnv("RESTRICTED_ACCESS_KEY", ""),
+		MaxUploadsPerClient:    getEnvInt("MAX_CONCURRENT_UPLOADS_PER_CLIENT", 0),
+		TrustedProxies:         getEnvList("TRUSTED_PROXIES"),
+		SearchTimeout:          time.Duration(getEnvInt("SEARCH_TIMEOUT_SECONDS", 300)) * time.Second,
+		SearchConcurrency:      getEnvInt("SEARCH_CONCURRENCY", 4),
+		CompactStorageJSON:     getEnvBool("COMPACT_STORAGE_JSON", false),
+		GzipEnabled:            getEnvBool("GZIP_ENABLED", true),
+		GzipMinSize:            getEnvInt("GZIP_MIN_SIZE", 1024),
+		PublicBaseURL:          strings.TrimRight(getEnv("PUBLIC_BASE_URL", ""), "/"),
+	}
+}
+
+// getEnv returns the value of an environment variable or a default
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvInt returns an integer environment variable or a default
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: Invalid value for %s: %s, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvFloat returns a float environment variable or a default
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Warning: Invalid value for %s: %s, using default %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvBool returns a boolean environment variable or a default
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: Invalid value for %s: %s, using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvExtensions returns a comma-separated list of file extensions from an
+// environment variable or a default, normalized to lowercase with a leading dot
+func getEnvExtensions(key, defaultValue string) []string {
+	extensions := parseExtensions(getEnv(key, defaultValue))
+	if len(extensions) == 0 {
+		log.Printf("Warning: No extensions in %s, using default %s", key, defaultValue)
+		return parseExtensions(defaultValue)
+	}
+	return extensions
+}
+
+// getEnvList returns a comma-separated environment variable as a list,
+// skipping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// parseExtensions splits a comma-separated extension list such as "mp4, .MOV"
+func parseExtensions(value string) []string {
+	var extensions []string
+	for _, ext := range strings.Split(value, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions
+}
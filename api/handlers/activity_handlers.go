@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"video-processing-backend/middleware"
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Activity event types sent on the activity stream
+const (
+	ActivityUploadStarted     = "upload_started"
+	ActivityAnalysisCompleted = "analysis_completed"
+	ActivityAnalysisFailed    = "analysis_failed"
+	ActivitySearchRun         = "search_run"
+	ActivityMatchFound        = "match_found"
+)
+
+var activityTypes = map[string]bool{
+	ActivityUploadStarted:     true,
+	ActivityAnalysisCompleted: true,
+	ActivityAnalysisFailed:    true,
+	ActivitySearchRun:         true,
+	ActivityMatchFound:        true,
+}
+
+// activityBufferSize is how many events a slow subscriber may fall behind
+// before further events are dropped for it
+const activityBufferSize = 64
+
+// activityHeartbeatInterval keeps idle streams from being closed by proxies
+const activityHeartbeatInterval = 30 * time.Second
+
+// ActivityEvent is a high-level event of the server's processing activity
+type ActivityEvent struct {
+	Type     string                 `json:"type"`
+	Time     time.Time              `json:"time"`
+	VideoID  string                 `json:"video_id,omitempty"`
+	SearchID string                 `json:"search_id,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+
+	restricted bool // Concerns a restricted video
+}
+
+// activityHub fans events out to the connected activity streams
+type activityHub struct {
+	mu          sync.Mutex
+	subscribers map[chan ActivityEvent]struct{}
+}
+
+var activity = &activityHub{subscribers: make(map[chan ActivityEvent]struct{})}
+
+// subscribe registers a new subscriber and returns its event channel
+func (h *activityHub) subscribe() chan ActivityEvent {
+	events := make(chan ActivityEvent, activityBufferSize)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[events] = struct{}{}
+	return events
+}
+
+// unsubscribe removes a subscriber registered with subscribe
+func (h *activityHub) unsubscribe(events chan ActivityEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, events)
+}
+
+// publish sends an event to every subscriber without blocking; subscribers
+// whose buffer is full miss the event
+func (h *activityHub) publish(event ActivityEvent) {
+	event.Time = time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for events := range h.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// publishVideoActivity publishes an event about a video
+func publishVideoActivity(eventType string, record *models.VideoRecord, data map[string]interface{}) {
+	activity.publish(ActivityEvent{
+		Type:       eventType,
+		VideoID:    record.ID,
+		Data:       data,
+		restricted: record.IsRestricted(),
+	})
+}
+
+// publishSearchActivity publishes a finished search and one event per
+// matched video. Matches in restricted videos only reach streams with the
+// restricted scope.
+func publishSearchActivity(searchID string, matches []FaceMatch, totalVideos int, partial bool) {
+	activity.publish(ActivityEvent{
+		Type:     ActivitySearchRun,
+		SearchID: searchID,
+		Data: map[string]interface{}{
+			"matches_found": len(matches),
+			"total_videos":  totalVideos,
+			"partial":       partial,
+		},
+	})
+	for _, match := range matches {
+		activity.publish(ActivityEvent{
+			Type:     ActivityMatchFound,
+			SearchID: searchID,
+			VideoID:  match.Video.ID,
+			Data: map[string]interface{}{
+				"matched_faces": len(match.MatchedFaces),
+				"similarity":    match.Similarity,
+			},
+			restricted: match.Video.IsRestricted(),
+		})
+	}
+}
+
+// ActivityStreamHandler streams server activity as server-sent events. The
+// optional types parameter takes a comma-separated list of event types to
+// receive. Events about restricted videos are only sent to callers with the
+// restricted scope.
+func ActivityStreamHandler(c *gin.Context) {
+	var wanted map[string]bool
+	if raw := c.Query("types"); raw != "" {
+		wanted = make(map[string]bool)
+		for _, eventType := range strings.Split(raw, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if !activityTypes[eventType] {
+				respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, fmt.Sprintf("Unknown activity type: %q", eventType))
+				return
+			}
+			wanted[eventType] = true
+		}
+	}
+	allowRestricted := middleware.HasRestrictedAccess(c)
+
+	events := activity.subscribe()
+	defer activity.unsubscribe(events)
+
+	heartbeat := time.NewTicker(activityHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// Send the headers right away so clients know the stream is open
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-events:
+			if (wanted != nil && !wanted[event.Type]) || (event.restricted && !allowRestricted) {
+				return true
+			}
+			c.SSEvent(event.Type, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		return true
+	})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAttachmentNamePart bounds each user-influenced part of a download name
+const maxAttachmentNamePart = 64
+
+// setAttachment marks the response as a download named after parts joined
+// with underscores, e.g. video_1703123456_faces_20231221.json. Every part is
+// sanitized since some, like original filenames, come from users.
+func setAttachment(c *gin.Context, ext string, parts ...string) {
+	var name []string
+	for _, part := range parts {
+		if part = sanitizeFilenamePart(part); part != "" {
+			name = append(name, part)
+		}
+	}
+	if len(name) == 0 {
+		name = []string{"download"}
+	}
+
+	filename := strings.Join(name, "_") + "." + sanitizeFilenamePart(ext)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+}
+
+// attachmentDate formats a time for use in a download name
+func attachmentDate(t time.Time) string {
+	return t.Format("20060102_150405")
+}
+
+// sanitizeFilenamePart keeps letters, digits, dots, dashes and underscores
+// of an ASCII name, replacing runs of anything else with a single underscore
+func sanitizeFilenamePart(part string) string {
+	var b strings.Builder
+	lastReplaced := false
+	for _, r := range part {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			b.WriteRune(r)
+			lastReplaced = false
+		case !lastReplaced:
+			b.WriteRune('_')
+			lastReplaced = true
+		}
+	}
+
+	// Leading dots would make hidden files, and edge underscores are noise
+	sanitized := strings.Trim(b.String(), "._")
+	if len(sanitized) > maxAttachmentNamePart {
+		sanitized = strings.Trim(sanitized[:maxAttachmentNamePart], "._")
+	}
+	return sanitized
+}
+
+// filenameStem returns a filename without its directory and extension
+func filenameStem(filename string) string {
+	base := filepath.Base(filename)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"video-processing-backend/middleware"
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Names of the entries in an export bundle
+const (
+	bundleVideosFile  = "videos.json"
+	bundleHistoryFile = "search_history.json"
+)
+
+// bundleFileDirs maps the file directories inside a bundle to where their
+// files are stored on disk
+var bundleFileDirs = map[string]string{
+	"videos":   videosDir,
+	"faces":    facesDir,
+	"searches": searchesDir,
+}
+
+// ImportReport summarizes what an import did, or would do in a dry run
+type ImportReport struct {
+	DryRun           bool `json:"dry_run"`
+	Videos           int  `json:"videos"`
+	ReplacedVideos   int  `json:"replaced_videos"`
+	Searches         int  `json:"searches"`
+	ReplacedSearches int  `json:"replaced_searches"`
+	Files            int  `json:"files"`
+	SkippedFiles     int  `json:"skipped_files"`
+}
+
+// ExportBundleHandler streams a ZIP bundle with the video records, the search
+// history and, with include_files=true, the video, face and search image files
+func ExportBundleHandler(c *gin.Context) {
+	includeFiles := c.Query("include_files") == "true"
+
+	// The bundle holds every record, so it would reveal restricted videos
+	if !middleware.HasRestrictedAccess(c) {
+		for _, record := range videoStorage.ListRecords() {
+			if record.IsRestricted() {
+				respondError(c, http.StatusForbidden, ErrCodeRestrictedAccess, "Exporting restricted videos requires the restricted access scope")
+				return
+			}
+		}
+	}
+
+	videosData, err := videoStorage.Snapshot()
+	if err != nil {
+		respondStorageError(c, err, "Failed to export video records")
+		return
+	}
+
+	historyData := []byte(`{"records": {}}`)
+	if searchHistory != nil {
+		if historyData, err = searchHistory.Snapshot(); err != nil {
+			respondStorageError(c, err, "Failed to export search history")
+			return
+		}
+	}
+
+	c.Header("Content-Type", "application/zip")
+	setAttachment(c, "zip", "trinetraguard_bundle", attachmentDate(time.Now()))
+	c.Status(http.StatusOK)
+
+	// Errors past this point can only be logged, the response has started
+	archive := zip.NewWriter(c.Writer)
+	defer archive.Close()
+
+	if err := writeBundleEntry(archive, bundleVideosFile, videosData); err != nil {
+		log.Printf("Error writing bundle: %v", err)
+		return
+	}
+	if err := writeBundleEntry(archive, bundleHistoryFile, historyData); err != nil {
+		log.Printf("Error writing bundle: %v", err)
+		return
+	}
+
+	if !includeFiles {
+		return
+	}
+
+	for _, record := range videoStorage.ListRecords() {
+		addBundleFile(archive, "videos", record.StoredPath)
+		for _, faceImage := range record.FaceImages {
+			addBundleFile(archive, "faces", facePath(faceImage))
+		}
+	}
+	if searchHistory != nil {
+		for _, record := range searchHistory.ListRecords() {
+			if record.SearchImagePath != "" {
+				addBundleFile(archive, "searches", record.SearchImagePath)
+			}
+		}
+	}
+}
+
+// writeBundleEntry writes an in-memory file to the bundle
+func writeBundleEntry(archive *zip.Writer, name string, data []byte) error {
+	writer, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// addBundleFile copies a file from disk into a directory of the bundle,
+// skipping files that no longer exist
+func addBundleFile(archive *zip.Writer, dir, filePath string) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Printf("Warning: Skipping %s in bundle: %v", filePath, err)
+		return
+	}
+	defer file.Close()
+
+	writer, err := archive.Create(path.Join(dir, filepath.Base(filePath)))
+	if err != nil {
+		log.Printf("Warning: Skipping %s in bundle: %v", filePath, err)
+		return
+	}
+	if _, err := io.Copy(writer, file); err != nil {
+		log.Printf("Warning: Failed to copy %s into bundle: %v", filePath, err)
+	}
+}
+
+// ImportBundleHandler restores a bundle produced by ExportBundleHandler.
+// Records are merged into the existing data, replacing records with the same
+// IDs. With dry_run=true the bundle is only validated and summarized.
+func ImportBundleHandler(c *gin.Context) {
+	dryRun := c.PostForm("dry_run") == "true" || c.Query("dry_run") == "true"
+
+	fileHeader, err := c.FormFile("bundle")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeMissingFile, "No bundle file provided")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to read bundle")
+		return
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, fileHeader.Size)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidBundle, "Bundle is not a valid ZIP file")
+		return
+	}
+
+	// Validate the whole bundle before changing anything
+	var videos struct {
+		Records map[string]*models.VideoRecord `json:"records"`
+	}
+	var history struct {
+		Records map[string]*models.SearchRecord `json:"records"`
+	}
+	var files []*zip.File
+	foundVideos := false
+
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		switch dir, name := path.Split(entry.Name); {
+		case entry.Name == bundleVideosFile:
+			if err := readBundleJSON(entry, &videos); err != nil {
+				respondError(c, http.StatusBadRequest, ErrCodeInvalidBundle, fmt.Sprintf("Invalid %s: %v", bundleVideosFile, err))
+				return
+			}
+			foundVideos = true
+		case entry.Name == bundleHistoryFile:
+			if err := readBundleJSON(entry, &history); err != nil {
+				respondError(c, http.StatusBadRequest, ErrCodeInvalidBundle, fmt.Sprintf("Invalid %s: %v", bundleHistoryFile, err))
+				return
+			}
+		case bundleFileDirs[path.Clean(dir)] != "" && name != "" && name != "." && name != "..":
+			files = append(files, entry)
+		default:
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidBundle, fmt.Sprintf("Unexpected bundle entry: %s", entry.Name))
+			return
+		}
+	}
+
+	if !foundVideos {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidBundle, "Bundle does not contain "+bundleVideosFile)
+		return
+	}
+
+	for id, record := range videos.Records {
+		if record == nil || record.ID != id {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidBundle, fmt.Sprintf("Invalid video record %s", id))
+			return
+		}
+		// Point the record at where its files are restored on this server
+		record.StoredPath = filepath.Join(videosDir, filepath.Base(record.StoredPath))
+	}
+	for id, record := range history.Records {
+		if record == nil || record.ID != id {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidBundle, fmt.Sprintf("Invalid search record %s", id))
+			return
+		}
+		if record.SearchImagePath != "" {
+			record.SearchImagePath = filepath.Join(searchesDir, filepath.Base(record.SearchImagePath))
+		}
+	}
+
+	// A public record could replace a restricted one, or point at its file
+	if !middleware.HasRestrictedAccess(c) && bundleTouchesRestricted(videos.Records) {
+		respondError(c, http.StatusForbidden, ErrCodeRestrictedAccess, "Importing or replacing restricted videos requires the restricted access scope")
+		return
+	}
+
+	report := ImportReport{
+		DryRun:   dryRun,
+		Videos:   len(videos.Records),
+		Searches: len(history.Records),
+		Files:    len(files),
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Bundle is valid",
+			"report":  report,
+		})
+		return
+	}
+
+	for _, entry := range files {
+		dir, name := path.Split(entry.Name)
+		err := extractBundleFile(entry, filepath.Join(bundleFileDirs[path.Clean(dir)], name))
+		if os.IsExist(err) {
+			report.SkippedFiles++
+			continue
+		}
+		if err != nil {
+			log.Printf("Error restoring %s: %v", entry.Name, err)
+			respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to restore bundle files")
+			return
+		}
+	}
+
+	if report.ReplacedVideos, err = videoStorage.ImportRecords(videos.Records); err != nil {
+		respondStorageError(c, err, "Failed to import video records")
+		return
+	}
+	if searchHistory != nil && len(history.Records) > 0 {
+		if report.ReplacedSearches, err = searchHistory.ImportRecords(history.Records); err != nil {
+			respondStorageError(c, err, "Failed to import search history")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bundle imported successfully",
+		"report":  report,
+	})
+}
+
+// bundleTouchesRestricted reports whether importing the records would add a
+// restricted video, replace one, or reuse the video file of one
+func bundleTouchesRestricted(records map[string]*models.VideoRecord) bool {
+	restrictedIDs := make(map[string]bool)
+	restrictedPaths := make(map[string]bool)
+	for _, existing := range videoStorage.ListRecords() {
+		if existing.IsRestricted() {
+			restrictedIDs[existing.ID] = true
+			restrictedPaths[filepath.Clean(existing.StoredPath)] = true
+		}
+	}
+
+	for id, record := range records {
+		if record.IsRestricted() || restrictedIDs[id] || restrictedPaths[record.StoredPath] {
+			return true
+		}
+	}
+	return false
+}
+
+// readBundleJSON decodes a JSON entry of the bundle
+func readBundleJSON(entry *zip.File, v interface{}) error {
+	reader, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return json.NewDecoder(reader).Decode(v)
+}
+
+// extractBundleFile writes a file entry of the bundle to disk. Existing files
+// are never overwritten, an error satisfying os.IsExist is returned instead.
+func extractBundleFile(entry *zip.File, destination string) error {
+	reader, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		os.Remove(destination)
+		return err
+	}
+	return file.Close()
+}
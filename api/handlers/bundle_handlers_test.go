@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"video-processing-backend/middleware"
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newBundleRequest builds an import request carrying a bundle with the given
+// video records
+func newBundleRequest(t *testing.T, records map[string]*models.VideoRecord) *http.Request {
+	t.Helper()
+
+	var bundle bytes.Buffer
+	archive := zip.NewWriter(&bundle)
+	data, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBundleEntry(archive, bundleVideosFile, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("bundle", "bundle.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bundle.Bytes())
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/import/bundle", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestImportBundleAccessScope(t *testing.T) {
+	const accessKey = "secret"
+	restrictedPath := filepath.Join(videosDir, "restricted.mp4")
+
+	tests := []struct {
+		name       string
+		record     *models.VideoRecord
+		withScope  bool
+		wantCode   int
+		wantImport bool
+	}{
+		{
+			name:     "restricted video without scope",
+			record:   &models.VideoRecord{ID: "video_new", StoredPath: "new.mp4", Visibility: models.VisibilityRestricted},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "replace restricted video without scope",
+			record:   &models.VideoRecord{ID: "video_restricted", StoredPath: "restricted.mp4", Visibility: models.VisibilityPublic},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "reuse restricted file without scope",
+			record:   &models.VideoRecord{ID: "video_new", StoredPath: "other/restricted.mp4", Visibility: models.VisibilityPublic},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:       "public video without scope",
+			record:     &models.VideoRecord{ID: "video_new", StoredPath: "new.mp4", Visibility: models.VisibilityPublic},
+			wantCode:   http.StatusOK,
+			wantImport: true,
+		},
+		{
+			name:       "replace restricted video with scope",
+			record:     &models.VideoRecord{ID: "video_restricted", StoredPath: "restricted.mp4", Visibility: models.VisibilityPublic},
+			withScope:  true,
+			wantCode:   http.StatusOK,
+			wantImport: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := useTestStorage(t)
+			existing := &models.VideoRecord{ID: "video_restricted", StoredPath: restrictedPath, Visibility: models.VisibilityRestricted}
+			if err := storage.AddRecord(existing); err != nil {
+				t.Fatal(err)
+			}
+
+			r := gin.New()
+			r.Use(middleware.AccessScope(accessKey))
+			r.POST("/import/bundle", ImportBundleHandler)
+
+			req := newBundleRequest(t, map[string]*models.VideoRecord{tt.record.ID: tt.record})
+			if tt.withScope {
+				req.Header.Set(middleware.AccessKeyHeader, accessKey)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			stored := storage.Records[tt.record.ID]
+			imported := stored != nil && stored.Visibility == tt.record.Visibility
+			if imported != tt.wantImport {
+				t.Errorf("stored record = %+v, want imported %v", stored, tt.wantImport)
+			}
+			if !tt.wantImport && !storage.Records["video_restricted"].IsRestricted() {
+				t.Error("restricted record was replaced by a denied import")
+			}
+		})
+	}
+}
+
+func TestExtractBundleFileKeepsExistingFiles(t *testing.T) {
+	var bundle bytes.Buffer
+	archive := zip.NewWriter(&bundle)
+	if err := writeBundleEntry(archive, "videos/clip.mp4", []byte("from bundle")); err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+
+	reader, err := zip.NewReader(bytes.NewReader(bundle.Bytes()), int64(bundle.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := reader.File[0]
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.mp4")
+	if err := os.WriteFile(existing, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := extractBundleFile(entry, existing); !os.IsExist(err) {
+		t.Errorf("extracting over an existing file: err = %v, want an os.IsExist error", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "original" {
+		t.Errorf("existing file = %q, want it unchanged", data)
+	}
+
+	created := filepath.Join(dir, "new", "clip.mp4")
+	if err := extractBundleFile(entry, created); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(created); string(data) != "from bundle" {
+		t.Errorf("new file = %q, want the bundle contents", data)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clipsDir caches recently extracted clips
+const clipsDir = "../storage/cache/clips"
+
+// maxCachedClips bounds the clip cache; the oldest clips are removed first
+const maxCachedClips = 20
+
+// streamCopyExtensions are containers whose streams can usually be copied
+// into an MP4 clip without re-encoding
+var streamCopyExtensions = []string{".mp4", ".mov", ".m4v"}
+
+// GetVideoClipHandler returns the part of a video between start and end
+// (seconds) as an MP4 attachment, cut with ffmpeg. Streams are copied when
+// the source container allows it, which cuts at the nearest keyframes.
+func GetVideoClipHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	start, err := strconv.ParseFloat(c.Query("start"), 64)
+	if err != nil || start < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "start must be a non-negative number of seconds")
+		return
+	}
+	end, err := strconv.ParseFloat(c.Query("end"), 64)
+	if err != nil || end <= start {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "end must be a number of seconds greater than start")
+		return
+	}
+	if maxClipDuration > 0 && end-start > maxClipDuration.Seconds() {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, fmt.Sprintf("Clips must not be longer than %.0f seconds", maxClipDuration.Seconds()))
+		return
+	}
+	if record.DurationSeconds > 0 && end > record.DurationSeconds {
+		respondError(c, http.StatusBadRequest, ErrCodeSegmentOutOfRange, fmt.Sprintf("end must not exceed the video duration of %.2f seconds", record.DurationSeconds))
+		return
+	}
+
+	info, err := os.Stat(record.StoredPath)
+	if err != nil {
+		respondError(c, http.StatusNotFound, ErrCodeVideoFileNotFound, "Video file not found")
+		return
+	}
+
+	startStr := strconv.FormatFloat(start, 'f', -1, 64)
+	endStr := strconv.FormatFloat(end, 'f', -1, 64)
+	clipPath := filepath.Join(clipsDir, fmt.Sprintf("%s_%s_%s.mp4", filepath.Base(record.ID), startStr, endStr))
+
+	// Reuse a clip cut since the video file last changed
+	if cached, err := os.Stat(clipPath); err != nil || cached.ModTime().Before(info.ModTime()) {
+		if err := extractClip(c.Request.Context(), record.StoredPath, clipPath, startStr, end-start); err != nil {
+			log.Printf("Error extracting clip %s-%s of %s: %v", startStr, endStr, record.ID, err)
+			respondError(c, http.StatusInternalServerError, ErrCodeProcessingFailed, "Failed to extract clip")
+			return
+		}
+		pruneClipCache()
+	}
+
+	setAttachment(c, "mp4", record.ID, "clip", startStr, endStr)
+	c.File(clipPath)
+}
+
+// extractClip cuts duration seconds from start out of a video into an MP4
+// file, copying the streams when possible and re-encoding otherwise
+func extractClip(ctx context.Context, videoPath, clipPath, start string, duration float64) error {
+	if err := os.MkdirAll(clipsDir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent requests never serve a partial clip
+	tmp, err := os.CreateTemp(clipsDir, "clip_*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	run := func(codecArgs ...string) error {
+		args := []string{"-v", "error", "-y", "-ss", start, "-i", videoPath,
+			"-t", strconv.FormatFloat(duration, 'f', -1, 64)}
+		args = append(args, codecArgs...)
+		args = append(args, "-movflags", "+faststart", "-f", "mp4", tmp.Name())
+
+		output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
+	err = fmt.Errorf("stream copy not supported for %s", filepath.Ext(videoPath))
+	if hasAllowedExtension(videoPath, streamCopyExtensions) {
+		err = run("-c", "copy", "-avoid_negative_ts", "make_zero")
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Re-encoding clip of %s: %v", videoPath, err)
+		err = run("-c:v", "libx264", "-preset", "veryfast", "-c:a", "aac")
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), clipPath)
+}
+
+// pruneClipCache removes the oldest cached clips beyond maxCachedClips
+func pruneClipCache() {
+	entries, err := os.ReadDir(clipsDir)
+	if err != nil {
+		return
+	}
+
+	type cachedClip struct {
+		path    string
+		modTime int64
+	}
+	var clips []cachedClip
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".mp4" {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			clips = append(clips, cachedClip{filepath.Join(clipsDir, entry.Name()), info.ModTime().UnixNano()})
+		}
+	}
+	if len(clips) <= maxCachedClips {
+		return
+	}
+
+	sort.Slice(clips, func(i, j int) bool { return clips[i].modTime > clips[j].modTime })
+	for _, clip := range clips[maxCachedClips:] {
+		if err := os.Remove(clip.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: Could not remove cached clip %s: %v", clip.path, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Machine-readable error codes returned alongside the human-readable message
+const (
+	ErrCodeRouteNotFound            = "route_not_found"
+	ErrCodeMethodNotAllowed         = "method_not_allowed"
+	ErrCodeInvalidParameter         = "invalid_parameter"
+	ErrCodeInvalidRequestBody       = "invalid_request_body"
+	ErrCodeValidationFailed         = "validation_failed"
+	ErrCodeMissingFile              = "missing_file"
+	ErrCodeInvalidFileFormat        = "invalid_file_format"
+	ErrCodeInvalidBundle            = "invalid_bundle"
+	ErrCodeConfirmationRequired     = "confirmation_required"
+	ErrCodeRestrictedAccess         = "restricted_access_required"
+	ErrCodeVideoNotFound            = "video_not_found"
+	ErrCodeDuplicateVideo           = "duplicate_video"
+	ErrCodeVideoFileNotFound        = "video_file_not_found"
+	ErrCodeVideoLocationMissing     = "video_location_missing"
+	ErrCodeVideoNotArchived         = "video_not_archived"
+	ErrCodeVideoAlreadyArchived     = "video_already_archived"
+	ErrCodeVideoNotFailed           = "video_not_failed"
+	ErrCodeChecksumUnavailable      = "checksum_unavailable"
+	ErrCodeChecksumMismatch         = "checksum_mismatch"
+	ErrCodeFaceNotFound             = "face_not_found"
+	ErrCodeSearchHistoryUnavailable = "search_history_unavailable"
+	ErrCodeSearchNotFound           = "search_not_found"
+	ErrCodeSearchImageNotFound      = "search_image_not_found"
+	ErrCodeProcessingFailed         = "processing_failed"
+	ErrCodeProcessingLogNotFound    = "processing_log_not_found"
+	ErrCodeVideoTooLong             = "video_too_long"
+	ErrCodeSegmentOutOfRange        = "segment_out_of_range"
+	ErrCodeStorageError             = "storage_error"
+	ErrCodeRequestTimeout           = "request_timeout"
+)
+
+// respondError writes a JSON error response with both a code and a message
+func respondError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"error": message,
+		"code":  code,
+	})
+}
+
+// FieldError describes one invalid field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// respondValidationErrors reports every invalid field of a request at once
+func respondValidationErrors(c *gin.Context, fieldErrors []FieldError) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":  fmt.Sprintf("Request has %d invalid field(s)", len(fieldErrors)),
+		"code":   ErrCodeValidationFailed,
+		"fields": fieldErrors,
+	})
+}
+
+// respondBindError reports a request body that could not be decoded. A value
+// of the wrong JSON type is reported against its field; anything else, such
+// as malformed JSON, is an invalid body.
+func respondBindError(c *gin.Context, err error) {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		respondValidationErrors(c, []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be a JSON %s", typeErr.Field, jsonTypeName(typeErr.Type.Kind().String())),
+		}})
+		return
+	}
+	respondError(c, http.StatusBadRequest, ErrCodeInvalidRequestBody, "Invalid request body")
+}
+
+// jsonTypeName names the JSON type that decodes into a Go kind
+func jsonTypeName(kind string) string {
+	switch kind {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "slice", "array":
+		return "array"
+	case "map", "struct":
+		return "object"
+	default:
+		return "number"
+	}
+}
+
+// respondStorageError maps a storage error to a response: missing records
+// become a 404 and anything else a 500 with the given message
+func respondStorageError(c *gin.Context, err error, message string) {
+	if errors.Is(err, models.ErrRecordNotFound) {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	log.Printf("%s: %v", message, err)
+	respondError(c, http.StatusInternalServerError, ErrCodeStorageError, message)
+}
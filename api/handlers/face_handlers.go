@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"video-processing-backend/middleware"
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// facesDir is where extracted face images are stored
+const facesDir = "../storage/faces"
+
+// resizedFacesDir caches scaled-down face images, keyed by their dimensions
+const resizedFacesDir = "../storage/cache/faces"
+
+// maxResizeDimension bounds the w and h parameters of ServeFaceHandler
+const maxResizeDimension = 4096
+
+// ServeFaceHandler serves a face image with its content type detected from the
+// file bytes rather than the extension, so crops saved without (or with the
+// wrong) extension are still served as images. With w and/or h the face is
+// scaled down to fit within those dimensions, keeping its aspect ratio.
+func ServeFaceHandler(c *gin.Context) {
+	// Only serve files directly inside the faces directory
+	filename := filepath.Base(c.Param("filename"))
+	facePath := filepath.Join(facesDir, filename)
+
+	// Never list directories
+	info, err := os.Stat(facePath)
+	if err != nil || info.IsDir() {
+		respondError(c, http.StatusNotFound, ErrCodeFaceNotFound, "Face image not found")
+		return
+	}
+
+	// Faces are named after their video, and restricted videos' faces are hidden too
+	if videoID, _, found := strings.Cut(filename, "_face_"); found {
+		// Read-only lookup: GetRecord would save access statistics for every thumbnail
+		found, _ := videoStorage.LookupRecords([]string{videoID})
+		if len(found) > 0 && found[0].IsRestricted() && !middleware.HasRestrictedAccess(c) {
+			respondError(c, http.StatusNotFound, ErrCodeFaceNotFound, "Face image not found")
+			return
+		}
+	}
+
+	width, err := parseDimensionParam(c, "w")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+	height, err := parseDimensionParam(c, "h")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+
+	if width > 0 || height > 0 {
+		if facePath, err = resizedFacePath(facePath, info, width, height); err != nil {
+			log.Printf("Error resizing face %s: %v", filename, err)
+			respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to resize face image")
+			return
+		}
+	}
+
+	file, err := os.Open(facePath)
+	if err != nil {
+		respondError(c, http.StatusNotFound, ErrCodeFaceNotFound, "Face image not found")
+		return
+	}
+	defer file.Close()
+
+	if info, err = file.Stat(); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to read face image")
+		return
+	}
+
+	// Sniff the content type from the first bytes of the file
+	header := make([]byte, 512)
+	n, _ := io.ReadFull(file, header)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to read face image")
+		return
+	}
+
+	c.Header("Content-Type", http.DetectContentType(header[:n]))
+	http.ServeContent(c.Writer, c.Request, filename, info.ModTime(), file)
+}
+
+// parseDimensionParam reads an optional positive pixel dimension from the query
+func parseDimensionParam(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	dimension, err := strconv.Atoi(value)
+	if err != nil || dimension < 1 || dimension > maxResizeDimension {
+		return 0, fmt.Errorf("%s must be an integer between 1 and %d", name, maxResizeDimension)
+	}
+	return dimension, nil
+}
+
+// FaceExport is one face of a video in a faces download
+type FaceExport struct {
+	Face             string              `json:"face"`
+	URL              string              `json:"url"`
+	Frame            int                 `json:"frame,omitempty"`             // 1-based index of the sampled frame
+	TimestampSeconds *float64            `json:"timestamp_seconds,omitempty"` // Time of the sampled frame, from FaceBox.Timestamp at the configured sampling interval
+	Box              *models.BoundingBox `json:"box,omitempty"`
+	PaddedBox        *models.BoundingBox `json:"padded_box,omitempty"`
+	Quality          *float64            `json:"quality,omitempty"`
+	ImageBase64      string              `json:"image_base64,omitempty"`
+}
+
+// DownloadFacesHandler returns the metadata of every face of a video as a JSON
+// attachment for offline analysis, with each face image embedded as base64
+// when include_images=true. Faces are streamed one at a time so embedding the
+// images of a crowded video does not hold them all in memory.
+func DownloadFacesHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+	includeImages := c.Query("include_images") == "true"
+
+	boxes := make(map[string]models.FaceBox, len(record.FaceBoxes))
+	for _, box := range record.FaceBoxes {
+		boxes[filepath.Base(box.Face)] = box
+	}
+
+	header, err := json.Marshal(gin.H{
+		"video_id":      record.ID,
+		"model_version": record.ModelVersion,
+		"count":         len(record.FaceImages),
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to encode faces")
+		return
+	}
+
+	c.Header("Content-Type", "application/json")
+	setAttachment(c, "json", record.ID, "faces", attachmentDate(time.Now()))
+	c.Status(http.StatusOK)
+
+	// Errors past this point can only be logged, the response has started.
+	// The header object is reopened to append the faces array.
+	writer := bufio.NewWriter(c.Writer)
+	defer writer.Flush()
+	writer.Write(header[:len(header)-1])
+	writer.WriteString(`,"faces":[`)
+
+	for i, faceImage := range record.FaceImages {
+		face := FaceExport{Face: faceImage, URL: faceURL(faceImage)}
+		if box, ok := boxes[filepath.Base(faceImage)]; ok {
+			timestamp := box.Timestamp()
+			quality := box.Quality
+			face.Frame = box.Frame
+			face.TimestampSeconds = &timestamp
+			face.Box = &box.Box
+			face.PaddedBox = &box.PaddedBox
+			face.Quality = &quality
+		}
+		if includeImages {
+			data, err := os.ReadFile(facePath(faceImage))
+			if err != nil {
+				log.Printf("Warning: Could not read face %s for download: %v", faceImage, err)
+			} else {
+				face.ImageBase64 = base64.StdEncoding.EncodeToString(data)
+			}
+		}
+
+		encoded, err := json.Marshal(face)
+		if err != nil {
+			log.Printf("Error encoding face %s for download: %v", faceImage, err)
+			return
+		}
+		if i > 0 {
+			writer.WriteByte(',')
+		}
+		if _, err := writer.Write(encoded); err != nil {
+			log.Printf("Error writing faces download for video %s: %v", record.ID, err)
+			return
+		}
+	}
+
+	writer.WriteString("]}")
+}
+
+// FaceDuplicate is a face crop found to duplicate a better crop of the same video
+type FaceDuplicate struct {
+	Face        string `json:"face"`
+	DuplicateOf string `json:"duplicate_of"`
+	Distance    int    `json:"distance"` // Hamming distance between the perceptual hashes
+}
+
+// DedupVideoFacesHandler finds near-identical face crops of a video. Faces
+// whose perceptual hashes differ by at most max_distance bits (default 6) are
+// treated as the same crop, of which the highest-quality one is kept. Each
+// stored face is normally a different person, and crops of different people
+// can hash alike, so by default the duplicates are only reported. They are
+// removed with confirm=true; the record is saved before any image is deleted.
+func DedupVideoFacesHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	maxDistance := duplicateHashDistance
+	if value := c.Query("max_distance"); value != "" {
+		distance, err := strconv.Atoi(value)
+		if err != nil || distance < 0 || distance > 64 {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "max_distance must be an integer between 0 and 64")
+			return
+		}
+		maxDistance = distance
+	}
+
+	confirm := c.PostForm("confirm")
+	if confirm == "" {
+		confirm = c.Query("confirm")
+	}
+	dryRun := confirm != "true"
+
+	checked, found := findDuplicateFaces(record, maxDistance)
+
+	removed := []string{}
+	uniqueFaces := record.UniqueFacesCount
+	if !dryRun && len(found) > 0 {
+		duplicates := make(map[string]bool, len(found))
+		for _, duplicate := range found {
+			duplicates[filepath.Base(duplicate.Face)] = true
+		}
+
+		keptFaces := []string{}
+		for _, face := range record.FaceImages {
+			if duplicates[filepath.Base(face)] {
+				removed = append(removed, face)
+			} else {
+				keptFaces = append(keptFaces, face)
+			}
+		}
+		keptBoxes := []models.FaceBox{}
+		for _, box := range record.FaceBoxes {
+			if !duplicates[filepath.Base(box.Face)] {
+				keptBoxes = append(keptBoxes, box)
+			}
+		}
+
+		// Update a copy so readers of the stored record never see it half-changed
+		updated := *record
+		updated.FaceImages = keptFaces
+		updated.FaceBoxes = keptBoxes
+		updated.UniqueFacesCount = len(keptFaces)
+		uniqueFaces = updated.UniqueFacesCount
+		if err := videoStorage.UpdateRecord(&updated); err != nil {
+			respondStorageError(c, err, "Failed to save deduplicated faces")
+			return
+		}
+
+		for _, face := range removed {
+			if err := os.Remove(facePath(face)); err != nil {
+				log.Printf("Warning: Could not remove duplicate face %s: %v", face, err)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"video_id":           record.ID,
+		"dry_run":            dryRun,
+		"checked":            checked,
+		"duplicates":         found,
+		"removed":            len(removed),
+		"removed_faces":      removed,
+		"unique_faces_count": uniqueFaces,
+	})
+}
+
+// findDuplicateFaces hashes the faces of a video, best quality first, and
+// returns how many were hashed and which ones duplicate a better crop
+func findDuplicateFaces(record *models.VideoRecord, maxDistance int) (int, []FaceDuplicate) {
+	quality := make(map[string]float64, len(record.FaceBoxes))
+	for _, box := range record.FaceBoxes {
+		quality[filepath.Base(box.Face)] = box.Quality
+	}
+
+	// Consider the best crops first so they are the ones kept
+	order := make([]string, len(record.FaceImages))
+	copy(order, record.FaceImages)
+	sort.SliceStable(order, func(i, j int) bool {
+		return quality[filepath.Base(order[i])] > quality[filepath.Base(order[j])]
+	})
+
+	type keptFace struct {
+		face string
+		hash uint64
+	}
+	var kept []keptFace
+	checked := 0
+	duplicates := []FaceDuplicate{}
+	for _, face := range order {
+		hash, err := perceptualHash(facePath(face))
+		if err != nil {
+			// Faces that cannot be read are left for reindexing to deal with
+			log.Printf("Warning: Could not hash face %s: %v", face, err)
+			continue
+		}
+		checked++
+
+		duplicate := false
+		for _, k := range kept {
+			if distance := hammingDistance(hash, k.hash); distance <= maxDistance {
+				duplicates = append(duplicates, FaceDuplicate{Face: face, DuplicateOf: k.face, Distance: distance})
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			kept = append(kept, keptFace{face: face, hash: hash})
+		}
+	}
+
+	return checked, duplicates
+}
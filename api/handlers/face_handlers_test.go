@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeTestFace stores a 64x64 face image whose pixels are white where bright
+// reports true and black elsewhere
+func writeTestFace(t *testing.T, face string, bright func(x, y int) bool) {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if bright(x, y) {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+
+	if err := os.MkdirAll(facesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(facePath(face))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDedupVideoFaces(t *testing.T) {
+	const (
+		worseCrop = "faces/video_1_face_001.png"
+		bestCrop  = "faces/video_1_face_002.png"
+		otherFace = "faces/video_1_face_003.png"
+	)
+
+	tests := []struct {
+		name        string
+		query       string
+		wantDryRun  bool
+		wantRemoved []string
+	}{
+		{"reports by default", "", true, nil},
+		{"removes when confirmed", "?confirm=true", false, []string{worseCrop}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestWorkDir(t)
+			storage := useTestStorage(t)
+
+			// Two crops of the same face, the better one with a slight difference,
+			// and a different face
+			leftHalf := func(x, y int) bool { return x < 32 }
+			writeTestFace(t, worseCrop, leftHalf)
+			writeTestFace(t, bestCrop, func(x, y int) bool { return leftHalf(x, y) || (x == 40 && y == 40) })
+			writeTestFace(t, otherFace, func(x, y int) bool { return y < 32 })
+
+			faces := []string{worseCrop, bestCrop, otherFace}
+			record := &models.VideoRecord{
+				ID:               "video_1",
+				Status:           "completed",
+				FaceImages:       faces,
+				UniqueFacesCount: len(faces),
+				FaceBoxes: []models.FaceBox{
+					{Face: worseCrop, Quality: 0.4},
+					{Face: bestCrop, Quality: 0.9},
+					{Face: otherFace, Quality: 0.6},
+				},
+			}
+			if err := storage.AddRecord(record); err != nil {
+				t.Fatal(err)
+			}
+
+			r := gin.New()
+			r.POST("/videos/:id/dedup-faces", DedupVideoFacesHandler)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/videos/video_1/dedup-faces"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			var response struct {
+				DryRun     bool            `json:"dry_run"`
+				Checked    int             `json:"checked"`
+				Duplicates []FaceDuplicate `json:"duplicates"`
+				Removed    []string        `json:"removed_faces"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+
+			if response.DryRun != tt.wantDryRun || response.Checked != len(faces) {
+				t.Errorf("dry_run = %v and checked = %d, want %v and %d", response.DryRun, response.Checked, tt.wantDryRun, len(faces))
+			}
+			if len(response.Duplicates) != 1 || response.Duplicates[0].Face != worseCrop || response.Duplicates[0].DuplicateOf != bestCrop {
+				t.Errorf("duplicates = %+v, want %s as a duplicate of %s", response.Duplicates, worseCrop, bestCrop)
+			}
+			if strings.Join(response.Removed, ",") != strings.Join(tt.wantRemoved, ",") {
+				t.Errorf("removed_faces = %v, want %v", response.Removed, tt.wantRemoved)
+			}
+
+			removed := make(map[string]bool)
+			for _, face := range tt.wantRemoved {
+				removed[face] = true
+			}
+			stored := storage.Records["video_1"]
+			if len(stored.FaceImages) != len(faces)-len(tt.wantRemoved) || stored.UniqueFacesCount != len(stored.FaceImages) || len(stored.FaceBoxes) != len(stored.FaceImages) {
+				t.Errorf("stored faces = %v with count %d and %d boxes", stored.FaceImages, stored.UniqueFacesCount, len(stored.FaceBoxes))
+			}
+			for _, face := range faces {
+				_, err := os.Stat(filepath.Join(facesDir, filepath.Base(face)))
+				if exists := err == nil; exists == removed[face] {
+					t.Errorf("%s exists = %v, want %v", face, exists, !removed[face])
+				}
+			}
+		})
+	}
+}
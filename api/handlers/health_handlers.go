@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pythonInterpreter is the interpreter used to run the face processing scripts
+const pythonInterpreter = "venv/bin/python3"
+
+// readinessState tracks which startup dependencies are ready
+type readinessState struct {
+	mu              sync.RWMutex
+	storageLoaded   bool
+	pythonAvailable bool
+	warmedUp        bool
+
+	storageIncidents []StorageIncident // Corrupt storage files replaced at startup
+}
+
+// StorageIncident is a corrupt storage file found when loading storage
+type StorageIncident struct {
+	File       string    `json:"file"`
+	BackupPath string    `json:"backup_path"`
+	Error      string    `json:"error"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+var readiness readinessState
+
+// ready reports whether all dependencies are available
+func (rs *readinessState) ready() bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.storageLoaded && rs.pythonAvailable && rs.warmedUp
+}
+
+// checks returns the status of each dependency
+func (rs *readinessState) checks() gin.H {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return gin.H{
+		"storage_loaded":   rs.storageLoaded,
+		"python_available": rs.pythonAvailable,
+		"warmed_up":        rs.warmedUp,
+	}
+}
+
+// markStorageLoaded records that the JSON storage files have been loaded
+func (rs *readinessState) markStorageLoaded() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.storageLoaded = true
+}
+
+// recordStorageIncident reports a corrupt storage file that was replaced with
+// an empty one, so the data loss shows up in the logs and the health check
+func (rs *readinessState) recordStorageIncident(corrupt *models.CorruptStorageError) {
+	log.Printf("ERROR: %v. Its data is missing until the backup is restored.", corrupt)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.storageIncidents = append(rs.storageIncidents, StorageIncident{
+		File:       corrupt.Path,
+		BackupPath: corrupt.BackupPath,
+		Error:      corrupt.Err.Error(),
+		DetectedAt: time.Now(),
+	})
+}
+
+// incidents returns the storage incidents recorded so far
+func (rs *readinessState) incidents() []StorageIncident {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return append([]StorageIncident{}, rs.storageIncidents...)
+}
+
+// warmUpTimeout bounds the first import of the Python face libraries
+const warmUpTimeout = 2 * time.Minute
+
+// WarmUp checks the external dependencies needed to process requests and
+// imports the Python face libraries once, so their files are cached before
+// the first upload pays for loading them. The server is marked ready to
+// receive traffic once it has run; it is meant to run in the background.
+func WarmUp() {
+	pythonAvailable := fileExists(pythonInterpreter) &&
+		fileExists(filepath.Join("python", "face_detect.py")) &&
+		fileExists(filepath.Join("python", "face_search.py"))
+
+	if pythonAvailable {
+		ctx, cancel := context.WithTimeout(context.Background(), warmUpTimeout)
+		defer cancel()
+
+		start := time.Now()
+		cmd := exec.CommandContext(ctx, pythonInterpreter, "-c", "import cv2, face_recognition, numpy")
+		cmd.Dir = "." // Set working directory to api root
+		if output, err := cmd.CombinedOutput(); err != nil {
+			log.Printf("Warning: Could not load the Python face libraries: %v: %s", err, strings.TrimSpace(string(output)))
+			pythonAvailable = false
+		} else {
+			log.Printf("Python face libraries loaded in %s", time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	readiness.mu.Lock()
+	defer readiness.mu.Unlock()
+	readiness.pythonAvailable = pythonAvailable
+	readiness.warmedUp = true
+}
+
+// fileExists reports whether a regular file exists at the path
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// LivenessHandler reports that the process is up
+func LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "alive",
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// ReadinessHandler reports whether the server is ready to receive traffic,
+// returning 503 until storage is loaded and dependencies are warmed up
+func ReadinessHandler(c *gin.Context) {
+	status := http.StatusOK
+	state := "ready"
+	if !readiness.ready() {
+		status = http.StatusServiceUnavailable
+		state = "not_ready"
+	}
+
+	c.JSON(status, gin.H{
+		"status":    state,
+		"checks":    readiness.checks(),
+		"timestamp": time.Now().Unix(),
+	})
+}
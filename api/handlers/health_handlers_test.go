@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadinessAfterWarmUp(t *testing.T) {
+	t.Cleanup(func() { readiness = readinessState{} })
+
+	r := gin.New()
+	r.GET("/readyz", ReadinessHandler)
+
+	// Each step changes the readiness state the way startup does
+	steps := []struct {
+		name      string
+		update    func(rs *readinessState)
+		wantCode  int
+		wantState string
+	}{
+		{"starting", func(rs *readinessState) {}, http.StatusServiceUnavailable, "not_ready"},
+		{"storage loaded", func(rs *readinessState) { rs.storageLoaded = true }, http.StatusServiceUnavailable, "not_ready"},
+		{"warm-up without Python", func(rs *readinessState) { rs.warmedUp = true }, http.StatusServiceUnavailable, "not_ready"},
+		{"warm-up finished", func(rs *readinessState) { rs.pythonAvailable = true }, http.StatusOK, "ready"},
+	}
+
+	for _, step := range steps {
+		readiness.mu.Lock()
+		step.update(&readiness)
+		readiness.mu.Unlock()
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var response struct {
+			Status string          `json:"status"`
+			Checks map[string]bool `json:"checks"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != step.wantCode || response.Status != step.wantState {
+			t.Errorf("%s: got %d %q, want %d %q", step.name, w.Code, response.Status, step.wantCode, step.wantState)
+		}
+		if response.Checks["warmed_up"] != readiness.warmedUp {
+			t.Errorf("%s: warmed_up check = %v, want %v", step.name, response.Checks["warmed_up"], readiness.warmedUp)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"video-processing-backend/models"
+)
+
+// duplicateHashDistance is the maximum Hamming distance between perceptual
+// hashes for two face crops to be considered the same face
+const duplicateHashDistance = 6
+
+// facePath returns the on-disk path of a stored face image reference such as "faces/x.jpg"
+func facePath(faceImage string) string {
+	return filepath.Join(facesDir, filepath.Base(faceImage))
+}
+
+// decodeImageFile decodes a JPEG, PNG or GIF image from disk
+func decodeImageFile(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+// fitWithin scales a size down to fit within maxWidth x maxHeight, keeping
+// its aspect ratio. A zero bound is unconstrained, and sizes are never scaled up.
+func fitWithin(width, height, maxWidth, maxHeight int) (int, int) {
+	scale := 1.0
+	if maxWidth > 0 && maxWidth < width {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && maxHeight < height {
+		scale = min(scale, float64(maxHeight)/float64(height))
+	}
+	if scale == 1.0 {
+		return width, height
+	}
+	return max(1, int(float64(width)*scale+0.5)), max(1, int(float64(height)*scale+0.5))
+}
+
+// resizedFacePath returns the path of a face image scaled down to fit within
+// maxWidth x maxHeight, creating it in the cache if it is missing or older
+// than the original. The original path is returned when no scaling is needed.
+func resizedFacePath(path string, info os.FileInfo, maxWidth, maxHeight int) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return "", err
+	}
+
+	width, height := fitWithin(config.Width, config.Height, maxWidth, maxHeight)
+	if width == config.Width && height == config.Height {
+		return path, nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	cachedPath := filepath.Join(resizedFacesDir, fmt.Sprintf("%s_%dx%d.jpg", name, width, height))
+	if cached, err := os.Stat(cachedPath); err == nil && !cached.ModTime().Before(info.ModTime()) {
+		return cachedPath, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(resizedFacesDir, 0755); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so concurrent requests never serve a partial image
+	tmp, err := os.CreateTemp(resizedFacesDir, name+"_*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := jpeg.Encode(tmp, scaleDown(img, width, height), &jpeg.Options{Quality: 90}); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), cachedPath); err != nil {
+		return "", err
+	}
+
+	return cachedPath, nil
+}
+
+// scaleDown resizes an image to width x height by averaging the source pixels
+// covered by each destination pixel
+func scaleDown(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/width, x0+1)
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1 && sy < bounds.Max.Y; sy++ {
+				for sx := x0; sx < x1 && sx < bounds.Max.X; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+			if count == 0 {
+				continue
+			}
+			scaled.Set(x, y, color.RGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(b / count),
+				A: uint16(a / count),
+			})
+		}
+	}
+
+	return scaled
+}
+
+// perceptualHash computes a 64-bit average hash of an image: the image is
+// reduced to 8x8 grayscale blocks and each bit records whether a block is
+// brighter than the mean. Visually similar images have a small Hamming distance.
+func perceptualHash(path string) (uint64, error) {
+	img, err := decodeImageFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	bounds := img.Bounds()
+	var blocks [64]float64
+	total := 0.0
+
+	for by := 0; by < 8; by++ {
+		y0 := bounds.Min.Y + by*bounds.Dy()/8
+		y1 := max(bounds.Min.Y+(by+1)*bounds.Dy()/8, y0+1)
+		for bx := 0; bx < 8; bx++ {
+			x0 := bounds.Min.X + bx*bounds.Dx()/8
+			x1 := max(bounds.Min.X+(bx+1)*bounds.Dx()/8, x0+1)
+
+			sum, count := 0.0, 0
+			for y := y0; y < y1 && y < bounds.Max.Y; y++ {
+				for x := x0; x < x1 && x < bounds.Max.X; x++ {
+					r, g, b, _ := img.At(x, y).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					count++
+				}
+			}
+			if count > 0 {
+				blocks[by*8+bx] = sum / float64(count)
+			}
+			total += blocks[by*8+bx]
+		}
+	}
+
+	mean := total / 64
+	var hash uint64
+	for i, value := range blocks {
+		if value > mean {
+			hash |= 1 << uint(i)
+		}
+	}
+
+	return hash, nil
+}
+
+// hammingDistance returns the number of differing bits between two hashes
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// newFaceMatch builds a search match for a video, collapsing near-duplicate
+// crops of the same face so each matched individual appears once with its
+// highest-similarity crop
+func newFaceMatch(video *models.VideoRecord, matchedFaces []matchedFace) FaceMatch {
+	// Consider the best matches first so they are kept as representatives
+	sorted := make([]matchedFace, len(matchedFaces))
+	copy(sorted, matchedFaces)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Similarity > sorted[j].Similarity
+	})
+
+	var keptHashes []uint64
+	bestFaces := []string{}
+	for _, face := range sorted {
+		hash, err := perceptualHash(facePath(face.Image))
+		if err == nil {
+			duplicate := false
+			for _, kept := range keptHashes {
+				if hammingDistance(hash, kept) <= duplicateHashDistance {
+					duplicate = true
+					break
+				}
+			}
+			if duplicate {
+				continue
+			}
+			keptHashes = append(keptHashes, hash)
+		}
+		bestFaces = append(bestFaces, face.Image)
+	}
+
+	similarity := 0.0
+	if len(sorted) > 0 {
+		similarity = sorted[0].Similarity
+	}
+
+	return FaceMatch{
+		Video:        video,
+		MatchedFaces: bestFaces,
+		MatchCount:   len(matchedFaces),
+		Similarity:   similarity,
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"video-processing-backend/config"
+)
+
+// StartRetentionJanitor periodically auto-archives old completed videos and
+// purges old archived records according to the configured thresholds
+func StartRetentionJanitor(cfg *config.Config) {
+	if cfg.AutoArchiveAfterDays <= 0 && cfg.PurgeArchivedAfterDays <= 0 {
+		log.Printf("Retention janitor disabled")
+		return
+	}
+
+	if cfg.JanitorInterval <= 0 {
+		log.Printf("Retention janitor disabled: invalid interval %s", cfg.JanitorInterval)
+		return
+	}
+
+	log.Printf("Retention janitor started (interval: %s, auto-archive after: %d days, purge after: %d days)",
+		cfg.JanitorInterval, cfg.AutoArchiveAfterDays, cfg.PurgeArchivedAfterDays)
+
+	go func() {
+		ticker := time.NewTicker(cfg.JanitorInterval)
+		defer ticker.Stop()
+
+		runRetention(cfg, time.Now())
+		for now := range ticker.C {
+			runRetention(cfg, now)
+		}
+	}()
+}
+
+// runRetention applies the retention policy relative to the given time
+func runRetention(cfg *config.Config, now time.Time) {
+	if cfg.AutoArchiveAfterDays > 0 {
+		cutoff := now.AddDate(0, 0, -cfg.AutoArchiveAfterDays)
+		archived, err := videoStorage.ArchiveCompletedBefore(cutoff)
+		if err != nil {
+			log.Printf("Retention janitor: failed to auto-archive videos: %v", err)
+		} else if len(archived) > 0 {
+			log.Printf("Retention janitor: auto-archived %d video(s): %v", len(archived), archived)
+		}
+	}
+
+	if cfg.PurgeArchivedAfterDays > 0 {
+		cutoff := now.AddDate(0, 0, -cfg.PurgeArchivedAfterDays)
+		purged, err := videoStorage.PurgeArchivedBefore(cutoff)
+		if err != nil {
+			log.Printf("Retention janitor: failed to purge archived videos: %v", err)
+		} else if len(purged) > 0 {
+			log.Printf("Retention janitor: purged %d archived video(s) and their files: %v", len(purged), purged)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"video-processing-backend/config"
+	"video-processing-backend/models"
+)
+
+func TestRunRetention(t *testing.T) {
+	useTestWorkDir(t)
+	storage := useTestStorage(t)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+	cfg := &config.Config{AutoArchiveAfterDays: 30, PurgeArchivedAfterDays: 90}
+
+	records := []struct {
+		record       *models.VideoRecord
+		wantArchived bool
+		wantPurged   bool
+	}{
+		{&models.VideoRecord{ID: "old_completed", Status: "completed", UploadTime: daysAgo(40), LastAccessed: daysAgo(40)}, true, false},
+		{&models.VideoRecord{ID: "recent_completed", Status: "completed", UploadTime: daysAgo(10), LastAccessed: daysAgo(10)}, false, false},
+		{&models.VideoRecord{ID: "old_failed", Status: "failed", UploadTime: daysAgo(40), LastAccessed: daysAgo(40)}, false, false},
+		{&models.VideoRecord{ID: "old_archived", Status: "completed", UploadTime: daysAgo(200), LastAccessed: daysAgo(100), IsArchived: true}, true, true},
+		{&models.VideoRecord{ID: "recent_archived", Status: "completed", UploadTime: daysAgo(200), LastAccessed: daysAgo(50), IsArchived: true}, true, false},
+		// Archived in this run, so it is not purged along with it
+		{&models.VideoRecord{ID: "ancient_completed", Status: "completed", UploadTime: daysAgo(400), LastAccessed: daysAgo(400)}, true, false},
+	}
+	for _, r := range records {
+		if err := storage.AddRecord(r.record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runRetention(cfg, now)
+
+	for _, r := range records {
+		stored, exists := storage.Records[r.record.ID]
+		if exists == r.wantPurged {
+			t.Errorf("%s: kept = %v, want %v", r.record.ID, exists, !r.wantPurged)
+			continue
+		}
+		if exists && stored.IsArchived != r.wantArchived {
+			t.Errorf("%s: archived = %v, want %v", r.record.ID, stored.IsArchived, r.wantArchived)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/url"
+	"path/filepath"
+
+	"video-processing-backend/config"
+)
+
+// publicBaseURL prefixes the links in responses so they work when the API is
+// served behind a proxy at another host or path; empty keeps links relative
+var publicBaseURL string
+
+// ConfigureLinks applies the public base URL from the configuration
+func ConfigureLinks(cfg *config.Config) {
+	publicBaseURL = cfg.PublicBaseURL
+}
+
+// publicURL returns the link to an API path such as /api/videos/x/file
+func publicURL(path string) string {
+	return publicBaseURL + path
+}
+
+// videoFileURL returns the link serving a video's file
+func videoFileURL(id string) string {
+	return publicURL("/api/videos/" + url.PathEscape(id) + "/file")
+}
+
+// faceURL returns the link serving a stored face reference such as "faces/x.jpg"
+func faceURL(face string) string {
+	return publicURL("/api/faces/" + url.PathEscape(filepath.Base(face)))
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logStreamBufferSize is how many lines a slow log stream may fall behind
+// before further lines are dropped for it
+const logStreamBufferSize = 256
+
+// processingLogFeed relays the output of running face detection to the
+// streams following a video's processing log
+type processingLogFeed struct {
+	mu      sync.Mutex
+	running map[string]*runningLog
+}
+
+// runningLog is the state of a video that is being processed
+type runningLog struct {
+	pending     []string // Output of the current run, not yet in the stored log
+	subscribers map[chan string]struct{}
+}
+
+var processingLogs = &processingLogFeed{running: make(map[string]*runningLog)}
+
+// start marks a video as being processed so its output can be followed
+func (f *processingLogFeed) start(videoID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running[videoID] = &runningLog{subscribers: make(map[chan string]struct{})}
+}
+
+// finish ends the streams following a video once its processing is over
+func (f *processingLogFeed) finish(videoID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if running := f.running[videoID]; running != nil {
+		for lines := range running.subscribers {
+			close(lines)
+		}
+		delete(f.running, videoID)
+	}
+}
+
+// publish relays a line of output without blocking; streams whose buffer is
+// full miss the line
+func (f *processingLogFeed) publish(videoID, line string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	running := f.running[videoID]
+	if running == nil {
+		return
+	}
+	running.pending = append(running.pending, line)
+	for lines := range running.subscribers {
+		select {
+		case lines <- line:
+		default:
+		}
+	}
+}
+
+// persist appends the output of a finished run to the video's stored log.
+// It holds the lock so a stream subscribing meanwhile sees every line once.
+func (f *processingLogFeed) persist(videoID string, startedAt time.Time, output []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := models.AppendProcessingLog(videoID, startedAt, output)
+	if running := f.running[videoID]; running != nil {
+		running.pending = nil
+	}
+	return err
+}
+
+// subscribe returns the stored log of a video, the output of its current run
+// that is not stored yet, and a channel of its further output. The channel
+// is nil when the video is not being processed.
+func (f *processingLogFeed) subscribe(videoID string) ([]byte, []string, chan string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored, err := os.ReadFile(models.ProcessingLogPath(videoID))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, nil, err
+	}
+
+	running := f.running[videoID]
+	if running == nil {
+		return stored, nil, nil, nil
+	}
+
+	lines := make(chan string, logStreamBufferSize)
+	running.subscribers[lines] = struct{}{}
+	return stored, append([]string{}, running.pending...), lines, nil
+}
+
+// unsubscribe removes a stream registered with subscribe
+func (f *processingLogFeed) unsubscribe(videoID string, lines chan string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if running := f.running[videoID]; running != nil {
+		delete(running.subscribers, lines)
+	}
+}
+
+// logLineWriter collects the output of a processing run and relays each
+// complete line to the streams following the video
+type logLineWriter struct {
+	videoID string
+	output  bytes.Buffer
+	partial []byte
+}
+
+// Write records the output and publishes the lines it completes
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.output.Write(p)
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		processingLogs.publish(w.videoID, string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush publishes a last line that did not end with a newline
+func (w *logLineWriter) flush() {
+	if len(w.partial) > 0 {
+		processingLogs.publish(w.videoID, string(w.partial))
+		w.partial = nil
+	}
+}
+
+// StreamVideoLogsHandler streams the processing log of a video as
+// server-sent events. The stored log is sent first, followed by new output
+// while the video is being processed, so reconnecting mid-run catches up.
+// The stream ends with an end event once processing is over, or right after
+// the stored log when the video is not being processed.
+func StreamVideoLogsHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	stored, pending, lines, err := processingLogs.subscribe(record.ID)
+	if err != nil {
+		log.Printf("Error reading processing log of %s: %v", record.ID, err)
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to read processing log")
+		return
+	}
+	if lines != nil {
+		defer processingLogs.unsubscribe(record.ID, lines)
+	} else if len(stored) == 0 {
+		respondError(c, http.StatusNotFound, ErrCodeProcessingLogNotFound, "No processing log recorded for this video")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if len(stored) > 0 {
+		for _, line := range strings.Split(strings.TrimRight(string(stored), "\n"), "\n") {
+			c.SSEvent("log", line)
+		}
+	}
+	for _, line := range pending {
+		c.SSEvent("log", line)
+	}
+	c.Writer.Flush()
+
+	if lines != nil {
+		heartbeat := time.NewTicker(activityHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case line, open := <-lines:
+				if !open {
+					return false
+				}
+				c.SSEvent("log", line)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			return true
+		})
+	}
+
+	if c.Request.Context().Err() == nil {
+		c.SSEvent("end", gin.H{"video_id": record.ID})
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// overlayBox is a face box passed to the overlay script
+type overlayBox struct {
+	Top    int    `json:"top"`
+	Right  int    `json:"right"`
+	Bottom int    `json:"bottom"`
+	Left   int    `json:"left"`
+	Label  string `json:"label"`
+}
+
+// overlaySampleWindow is how far t may be from a sampled frame for the
+// overlay to show that frame; half the default sampling interval of 1 second
+const overlaySampleWindow = 0.5
+
+// GetVideoOverlayHandler returns a frame near timestamp t (seconds) as a JPEG
+// with the boxes of the faces detected in it drawn on it. When a sampled
+// frame with faces lies within overlaySampleWindow of t, that exact frame is
+// drawn so the boxes line up. Each face keeps the box of the frame its crop
+// was taken from, so only faces best seen in that frame are drawn.
+func GetVideoOverlayHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	timestamp, err := strconv.ParseFloat(c.Query("t"), 64)
+	if err != nil || timestamp < 0 {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "t must be a non-negative number of seconds")
+		return
+	}
+	if record.DurationSeconds > 0 && timestamp > record.DurationSeconds {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, fmt.Sprintf("t must not exceed the video duration of %.2f seconds", record.DurationSeconds))
+		return
+	}
+
+	if _, err := os.Stat(record.StoredPath); os.IsNotExist(err) {
+		respondError(c, http.StatusNotFound, ErrCodeVideoFileNotFound, "Video file not found")
+		return
+	}
+
+	// Label faces by their position in the video's face list
+	faceNumbers := make(map[string]int, len(record.FaceImages))
+	for i, face := range record.FaceImages {
+		faceNumbers[filepath.Base(face)] = i + 1
+	}
+
+	// Snap to the nearest sampled frame that has faces
+	sampleTime, found := 0.0, false
+	for _, faceBox := range record.FaceBoxes {
+		distance := math.Abs(faceBox.Timestamp() - timestamp)
+		if distance <= overlaySampleWindow && (!found || distance < math.Abs(sampleTime-timestamp)) {
+			sampleTime, found = faceBox.Timestamp(), true
+		}
+	}
+	if found {
+		timestamp = sampleTime
+	}
+
+	boxes := []overlayBox{}
+	for _, faceBox := range record.FaceBoxes {
+		if !found || faceBox.Timestamp() != sampleTime {
+			continue
+		}
+		boxes = append(boxes, overlayBox{
+			Top:    faceBox.Box.Top,
+			Right:  faceBox.Box.Right,
+			Bottom: faceBox.Box.Bottom,
+			Left:   faceBox.Box.Left,
+			Label:  fmt.Sprintf("Person %d", faceNumbers[filepath.Base(faceBox.Face)]),
+		})
+	}
+
+	output, err := os.CreateTemp("", "overlay_*.jpg")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to create overlay image")
+		return
+	}
+	output.Close()
+	defer os.Remove(output.Name())
+
+	if err := renderOverlay(c, record.StoredPath, timestamp, boxes, output.Name()); err != nil {
+		log.Printf("Error rendering overlay for video %s: %v", record.ID, err)
+		respondError(c, http.StatusInternalServerError, ErrCodeProcessingFailed, "Failed to render overlay")
+		return
+	}
+
+	c.Header("Content-Type", "image/jpeg")
+	c.File(output.Name())
+}
+
+// renderOverlay runs the overlay script to write the frame with its boxes to outputPath
+func renderOverlay(c *gin.Context, videoPath string, timestamp float64, boxes []overlayBox, outputPath string) error {
+	pythonScriptPath := filepath.Join("python", "frame_overlay.py")
+	if _, err := os.Stat(pythonScriptPath); os.IsNotExist(err) {
+		return fmt.Errorf("Python overlay script not found: %s", pythonScriptPath)
+	}
+
+	boxesJSON, err := json.Marshal(boxes)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(c.Request.Context(), pythonInterpreter, pythonScriptPath, videoPath,
+		"--time", strconv.FormatFloat(timestamp, 'f', -1, 64),
+		"--boxes", string(boxesJSON),
+		"--output", outputPath)
+	cmd.Dir = "." // Set working directory to api root
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Printf("Overlay Python output: %s", string(output))
+		if jsonStr, jsonErr := extractLastJSONObject(string(output)); jsonErr == nil {
+			var result struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal([]byte(jsonStr), &result) == nil && result.Error != "" {
+				return fmt.Errorf("overlay script failed: %s", result.Error)
+			}
+		}
+		return fmt.Errorf("overlay script execution failed: %v", err)
+	}
+	return nil
+}
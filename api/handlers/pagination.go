@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPageLimit is the largest page size list endpoints accept
+const maxPageLimit = 1000
+
+// Pagination describes the page of a list response. Limit is 0 when the
+// client did not ask for a page size and all remaining items were returned.
+type Pagination struct {
+	Total   int  `json:"total"`
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	HasMore bool `json:"has_more"`
+
+	// Cursor for the next page of lists that support the after parameter
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// parsePagination reads the optional limit and offset query parameters
+func parsePagination(c *gin.Context) (limit, offset int, err error) {
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be an integer between 1 and %d", maxPageLimit)
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// paginate returns the page of items selected by limit and offset along with
+// its Pagination. A limit of 0 returns everything from offset on.
+func paginate[T any](items []T, limit, offset int) ([]T, Pagination) {
+	page := Pagination{Total: len(items), Limit: limit, Offset: offset}
+
+	start := min(offset, len(items))
+	end := len(items)
+	if limit > 0 {
+		end = min(start+limit, len(items))
+	}
+	page.HasMore = end < len(items)
+
+	// Never encode an empty page as null
+	items = items[start:end]
+	if items == nil {
+		items = []T{}
+	}
+	return items, page
+}
+
+// sortNewestFirst orders video records by upload time, newest first, so pages
+// are stable across requests
+func sortNewestFirst(records []*models.VideoRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].UploadTime.Equal(records[j].UploadTime) {
+			return records[i].ID > records[j].ID
+		}
+		return records[i].UploadTime.After(records[j].UploadTime)
+	})
+}
+
+// videoCursor is a position in a list of videos ordered by sortNewestFirst
+type videoCursor struct {
+	uploadTime time.Time
+	id         string
+}
+
+// encodeVideoCursor returns an opaque cursor pointing just past a record
+func encodeVideoCursor(record *models.VideoRecord) string {
+	raw := record.UploadTime.UTC().Format(time.RFC3339Nano) + "|" + record.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseVideoCursor decodes a cursor made by encodeVideoCursor
+func parseVideoCursor(value string) (videoCursor, error) {
+	invalid := fmt.Errorf("after must be a next_cursor returned by a previous page")
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return videoCursor{}, invalid
+	}
+	timePart, id, found := strings.Cut(string(raw), "|")
+	if !found || id == "" {
+		return videoCursor{}, invalid
+	}
+	uploadTime, err := time.Parse(time.RFC3339Nano, timePart)
+	if err != nil {
+		return videoCursor{}, invalid
+	}
+	return videoCursor{uploadTime: uploadTime, id: id}, nil
+}
+
+// recordsAfter returns the records that come after the cursor in records
+// ordered by sortNewestFirst. Since the cursor is a position rather than an
+// index, videos added or removed meanwhile do not shift later pages.
+func recordsAfter(records []*models.VideoRecord, cursor videoCursor) []*models.VideoRecord {
+	i := sort.Search(len(records), func(i int) bool {
+		record := records[i]
+		if record.UploadTime.Equal(cursor.uploadTime) {
+			return record.ID < cursor.id
+		}
+		return record.UploadTime.Before(cursor.uploadTime)
+	})
+	return records[i:]
+}
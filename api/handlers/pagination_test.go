@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// useTestStorage points the handlers at an empty video storage in a
+// temporary directory for the duration of the test
+func useTestStorage(t *testing.T) *models.VideoStorage {
+	t.Helper()
+	previous := videoStorage
+	videoStorage = models.NewVideoStorage(filepath.Join(t.TempDir(), "videos.json"))
+	t.Cleanup(func() { videoStorage = previous })
+	return videoStorage
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name          string
+		limit, offset int
+		want          []int
+		wantHasMore   bool
+	}{
+		{"everything", 0, 0, []int{1, 2, 3, 4, 5}, false},
+		{"first page", 2, 0, []int{1, 2}, true},
+		{"last page", 2, 4, []int{5}, false},
+		{"exact end", 5, 0, []int{1, 2, 3, 4, 5}, false},
+		{"offset only", 0, 3, []int{4, 5}, false},
+		{"past the end", 2, 10, []int{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, page := paginate(items, tt.limit, tt.offset)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+			if got == nil {
+				t.Error("items = nil, want an empty slice")
+			}
+			if page.Total != len(items) || page.HasMore != tt.wantHasMore {
+				t.Errorf("pagination = %+v, want total %d and has_more %v", page, len(items), tt.wantHasMore)
+			}
+		})
+	}
+}
+
+func TestParseVideoCursor(t *testing.T) {
+	record := &models.VideoRecord{ID: "video_1", UploadTime: time.Date(2023, 12, 21, 10, 30, 0, 123, time.UTC)}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"round trip", encodeVideoCursor(record), false},
+		{"not base64", "!!!", true},
+		{"no separator", "MjAyMy0xMi0yMVQxMDozMDowMFo", true},
+		{"bad time", "bm90LWEtdGltZXx2aWRlb18x", true},
+		{"no id", "MjAyMy0xMi0yMVQxMDozMDowMFp8", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := parseVideoCursor(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (cursor.id != record.ID || !cursor.uploadTime.Equal(record.UploadTime)) {
+				t.Errorf("cursor = %+v, want %s at %s", cursor, record.ID, record.UploadTime)
+			}
+		})
+	}
+}
+
+func TestListVideosCursorWithGrowingList(t *testing.T) {
+	storage := useTestStorage(t)
+	base := time.Date(2023, 12, 21, 10, 0, 0, 0, time.UTC)
+
+	addVideo := func(id string, uploadTime time.Time) {
+		if err := storage.AddRecord(&models.VideoRecord{ID: id, UploadTime: uploadTime, Status: "completed"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Two videos share an upload time so the ID tiebreak is exercised
+	original := []string{"video_a", "video_b", "video_c", "video_d", "video_e"}
+	for i, id := range original {
+		addVideo(id, base.Add(time.Duration(i/2)*time.Minute))
+	}
+
+	r := gin.New()
+	r.GET("/videos", ListVideosHandler)
+
+	seen := make(map[string]int)
+	after := ""
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatal("pagination did not end")
+		}
+
+		query := url.Values{"limit": {"2"}}
+		if after != "" {
+			query.Set("after", after)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/videos?"+query.Encode(), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("page %d: status %d: %s", page, w.Code, w.Body.String())
+		}
+
+		var response struct {
+			Videos     []models.VideoRecord `json:"videos"`
+			Pagination Pagination           `json:"pagination"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		for _, video := range response.Videos {
+			seen[video.ID]++
+		}
+
+		// Newer uploads arrive while the client is paging
+		if page == 0 {
+			addVideo("video_new_1", base.Add(time.Hour))
+			addVideo("video_new_2", base.Add(2*time.Hour))
+		}
+
+		if response.Pagination.NextCursor == "" {
+			break
+		}
+		after = response.Pagination.NextCursor
+	}
+
+	for _, id := range original {
+		if seen[id] != 1 {
+			t.Errorf("%s seen %d times, want once", id, seen[id])
+		}
+	}
+	for id, count := range seen {
+		if count > 1 {
+			t.Errorf("%s seen %d times", id, count)
+		}
+	}
+}
+
+func TestListVideosRejectsInvalidCursor(t *testing.T) {
+	useTestStorage(t)
+
+	r := gin.New()
+	r.GET("/videos", ListVideosHandler)
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"malformed cursor", "after=!!!"},
+		{"cursor with offset", "after=" + encodeVideoCursor(&models.VideoRecord{ID: "video_1"}) + "&offset=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/videos?"+tt.query, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotFoundHandler answers requests for paths that have no route
+func NotFoundHandler(c *gin.Context) {
+	respondError(c, http.StatusNotFound, ErrCodeRouteNotFound, "No route for "+c.Request.URL.Path)
+}
+
+// MethodNotAllowedHandler answers requests for paths that have routes, but
+// not for the request's method, listing the valid methods in the Allow
+// header. OPTIONS requests that are not CORS preflights get the same header
+// with 204 No Content.
+func MethodNotAllowedHandler(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed := allowedMethods(engine.Routes(), c.Request.URL.Path)
+		c.Header("Allow", strings.Join(allowed, ", "))
+
+		if c.Request.Method == http.MethodOptions {
+			c.Status(http.StatusNoContent)
+			return
+		}
+
+		respondError(c, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed,
+			fmt.Sprintf("Method %s is not allowed for %s", c.Request.Method, c.Request.URL.Path))
+	}
+}
+
+// allowedMethods returns the methods of the routes matching a path, plus OPTIONS
+func allowedMethods(routes gin.RoutesInfo, path string) []string {
+	methods := map[string]bool{http.MethodOptions: true}
+	for _, route := range routes {
+		if routeMatches(route.Path, path) {
+			methods[route.Method] = true
+		}
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for method := range methods {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// routeMatches reports whether a request path matches a route pattern with
+// :param and *catchAll segments
+func routeMatches(pattern, path string) bool {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "*") {
+			return true
+		}
+		if i >= len(pathParts) {
+			return false
+		}
+		if strings.HasPrefix(part, ":") {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return len(patternParts) == len(pathParts)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldSchema describes one JSON field of a response
+type FieldSchema struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Unit     string        `json:"unit,omitempty"`
+	Optional bool          `json:"optional"` // Omitted from the JSON when empty
+	Fields   []FieldSchema `json:"fields,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// describeFields lists the JSON fields of a struct type using its json tags,
+// with units taken from optional `unit` tags, so the schema follows the models
+func describeFields(t reflect.Type) []FieldSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	fields := []FieldSchema{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		// Embedded structs without a name contribute their fields directly
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			fields = append(fields, describeFields(field.Type)...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := FieldSchema{
+			Name:     name,
+			Type:     describeType(field.Type),
+			Unit:     field.Tag.Get("unit"),
+			Optional: strings.Contains(options, "omitempty"),
+		}
+		if elem := structElem(field.Type); elem != nil {
+			schema.Fields = describeFields(elem)
+		}
+		fields = append(fields, schema)
+	}
+	return fields
+}
+
+// describeType returns a JSON type name such as "array of string"
+func describeType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return "timestamp (RFC 3339 string)"
+	case t.Kind() == reflect.String:
+		return "string"
+	case t.Kind() == reflect.Bool:
+		return "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return "number"
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return "array of " + describeType(t.Elem())
+	default:
+		return "object"
+	}
+}
+
+// structElem returns the struct type a field holds directly or as array
+// elements, or nil if it does not hold structs with their own fields
+func structElem(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+	return t
+}
+
+// GetAnalysisSchemaHandler describes the fields of the video records holding
+// face detection results and of face search responses
+func GetAnalysisSchemaHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"video":       describeFields(reflect.TypeOf(models.VideoRecord{})),
+		"face_search": describeFields(reflect.TypeOf(FaceSearchResponse{})),
+	})
+}
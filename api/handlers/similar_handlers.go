@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SimilarVideo is a video sharing people with the video being compared
+type SimilarVideo struct {
+	Video        *models.VideoRecord `json:"video"`
+	CommonFaces  int                 `json:"common_faces"`          // Faces of the compared video seen in this one
+	MatchedFaces []string            `json:"matched_faces"`         // This video's faces that matched
+	Similarity   float64             `json:"similarity" unit:"0-1"` // Highest similarity of any matched face
+}
+
+// SimilarVideosHandler finds videos that likely show the same people as a
+// video. Each face of the video is compared with the faces of every other
+// completed video, and videos where at least min_common (default 1) of the
+// faces were found are returned, most shared faces first.
+func SimilarVideosHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	minCommon := 1
+	if value := c.Query("min_common"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "min_common must be a positive integer")
+			return
+		}
+		minCommon = parsed
+	}
+
+	metric, err := parseDistanceMetric(c.Query("distance_metric"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+
+	// Candidate faces, and the video each belongs to
+	owners := make(map[string]*models.VideoRecord)
+	var candidateFaces []string
+	for _, other := range visibleRecords(c, videoStorage.ListRecords()) {
+		if other.ID == record.ID || other.Status != "completed" {
+			continue
+		}
+		for _, face := range other.FaceImages {
+			owners[filepath.Base(face)] = other
+			candidateFaces = append(candidateFaces, face)
+		}
+	}
+
+	type overlap struct {
+		video   *models.VideoRecord
+		common  int
+		matched map[string]bool
+		best    float64
+	}
+	overlaps := make(map[string]*overlap)
+
+	ctx := c.Request.Context()
+	partial := false
+	if len(candidateFaces) > 0 {
+		for _, face := range record.FaceImages {
+			if ctx.Err() != nil {
+				partial = true
+				break
+			}
+
+			matchedFaces, err := compareFacesWithSearchImage(ctx, facePath(face), candidateFaces, metric)
+			if err != nil {
+				if ctx.Err() != nil {
+					partial = true
+					break
+				}
+				log.Printf("Error comparing face %s with other videos: %v", face, err)
+				continue
+			}
+
+			// Count each face of this video once per other video it appears in
+			seenIn := make(map[string]bool)
+			for _, match := range matchedFaces {
+				other := owners[filepath.Base(match.Image)]
+				if other == nil {
+					continue
+				}
+				entry := overlaps[other.ID]
+				if entry == nil {
+					entry = &overlap{video: other, matched: make(map[string]bool)}
+					overlaps[other.ID] = entry
+				}
+				if !seenIn[other.ID] {
+					seenIn[other.ID] = true
+					entry.common++
+				}
+				entry.matched[match.Image] = true
+				entry.best = max(entry.best, match.Similarity)
+			}
+		}
+	}
+
+	if partial && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Printf("Similar video search for %s exceeded the request timeout", record.ID)
+		respondError(c, http.StatusRequestTimeout, ErrCodeRequestTimeout, "Similar video search timed out")
+		return
+	}
+
+	similar := []SimilarVideo{}
+	for _, entry := range overlaps {
+		if entry.common < minCommon {
+			continue
+		}
+
+		matched := make([]string, 0, len(entry.matched))
+		for face := range entry.matched {
+			matched = append(matched, face)
+		}
+		sort.Strings(matched)
+
+		similar = append(similar, SimilarVideo{
+			Video:        entry.video,
+			CommonFaces:  entry.common,
+			MatchedFaces: matched,
+			Similarity:   entry.best,
+		})
+	}
+
+	// Most shared people first, then the closest match
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].CommonFaces != similar[j].CommonFaces {
+			return similar[i].CommonFaces > similar[j].CommonFaces
+		}
+		return similar[i].Similarity > similar[j].Similarity
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"video_id":        record.ID,
+		"min_common":      minCommon,
+		"distance_metric": metric,
+		"similar":         similar,
+		"count":           len(similar),
+	})
+}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"video-processing-backend/config"
+	"video-processing-backend/middleware"
 	"video-processing-backend/models"
 
 	"github.com/gin-gonic/gin"
@@ -16,14 +22,22 @@ import (
 var videoStorage *models.VideoStorage
 
 // InitializeStorage initializes the video storage system
-func InitializeStorage() {
+func InitializeStorage(cfg *config.Config) {
 	videoStorage = models.NewVideoStorage("../storage/data/videos.json")
-	if err := videoStorage.Load(); err != nil {
+	videoStorage.SetCompact(cfg.CompactStorageJSON)
+	var corrupt *models.CorruptStorageError
+	if err := videoStorage.Load(); errors.As(err, &corrupt) {
+		readiness.recordStorageIncident(corrupt)
+	} else if err != nil {
 		panic("Failed to load video storage: " + err.Error())
 	}
+	readiness.markStorageLoaded()
 
 	searchHistory = models.NewSearchHistory("../storage/data/search_history.json")
-	if err := searchHistory.Load(); err != nil {
+	searchHistory.SetCompact(cfg.CompactStorageJSON)
+	if err := searchHistory.Load(); errors.As(err, &corrupt) {
+		readiness.recordStorageIncident(corrupt)
+	} else if err != nil {
 		log.Printf("Warning: Failed to load search history: %v", err)
 	}
 }
@@ -33,43 +47,160 @@ func GetVideoStorage() *models.VideoStorage {
 	return videoStorage
 }
 
-// ListVideosHandler returns all video records (active and archived)
+// videoIDParam returns the video ID from the route and attaches it to the access log
+func videoIDParam(c *gin.Context) string {
+	id := c.Param("id")
+	middleware.SetLogField(c, "video_id", id)
+	return id
+}
+
+// ListVideosHandler returns all video records (active and archived),
+// newest first and optionally paginated with limit and either offset or the
+// after cursor. The reviewed parameter filters on the review flag.
 func ListVideosHandler(c *gin.Context) {
-	records := videoStorage.ListRecords()
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+
+	// A cursor from a previous page can be used instead of an offset
+	var cursor *videoCursor
+	if after := c.Query("after"); after != "" {
+		if offset > 0 {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "after and offset cannot be combined")
+			return
+		}
+		parsed, err := parseVideoCursor(after)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+			return
+		}
+		cursor = &parsed
+	}
+
+	// Optionally keep only reviewed or unreviewed videos
+	var reviewed *bool
+	if value := c.Query("reviewed"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "reviewed must be true or false")
+			return
+		}
+		reviewed = &parsed
+	}
+
+	records := visibleRecords(c, videoStorage.ListRecords())
+	if reviewed != nil {
+		filtered := []*models.VideoRecord{}
+		for _, record := range records {
+			if record.Reviewed == *reviewed {
+				filtered = append(filtered, record)
+			}
+		}
+		records = filtered
+	}
+	sortNewestFirst(records)
+	if cursor != nil {
+		records = recordsAfter(records, *cursor)
+	}
+	records, page := paginate(records, limit, offset)
+	if page.HasMore {
+		page.NextCursor = encodeVideoCursor(records[len(records)-1])
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"videos": records,
-		"count":  len(records),
+		"videos":     records,
+		"count":      len(records),
+		"pagination": page,
 	})
 }
 
-// ListActiveVideosHandler returns only active video records
+// ListActiveVideosHandler returns only active video records,
+// newest first and optionally paginated with limit and offset
 func ListActiveVideosHandler(c *gin.Context) {
-	records := videoStorage.ListActiveRecords()
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+
+	records := visibleRecords(c, videoStorage.ListActiveRecords())
+	sortNewestFirst(records)
+	records, page := paginate(records, limit, offset)
+
 	c.JSON(http.StatusOK, gin.H{
-		"videos": records,
-		"count":  len(records),
-		"type":   "active",
+		"videos":     records,
+		"count":      len(records),
+		"type":       "active",
+		"pagination": page,
 	})
 }
 
-// ListArchivedVideosHandler returns only archived video records (history)
+// ListArchivedVideosHandler returns only archived video records (history),
+// newest first and optionally paginated with limit and offset
 func ListArchivedVideosHandler(c *gin.Context) {
-	records := videoStorage.ListArchivedRecords()
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+
+	records := visibleRecords(c, videoStorage.ListArchivedRecords())
+	sortNewestFirst(records)
+	records, page := paginate(records, limit, offset)
+
 	c.JSON(http.StatusOK, gin.H{
-		"videos": records,
-		"count":  len(records),
-		"type":   "archived",
+		"videos":     records,
+		"count":      len(records),
+		"type":       "archived",
+		"pagination": page,
+	})
+}
+
+// UnanalyzedVideo is a video without a successful analysis and how long it has been in that state
+type UnanalyzedVideo struct {
+	*models.VideoRecord
+	SecondsInStatus float64 `json:"seconds_in_status"`
+}
+
+// ListUnanalyzedVideosHandler returns videos that failed or are still processing,
+// longest-waiting first, so operators can retry or investigate them
+func ListUnanalyzedVideosHandler(c *gin.Context) {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+
+	now := time.Now()
+	videos := []UnanalyzedVideo{}
+	for _, record := range visibleRecords(c, videoStorage.ListUnanalyzedRecords()) {
+		videos = append(videos, UnanalyzedVideo{
+			VideoRecord:     record,
+			SecondsInStatus: record.TimeInStatus(now).Seconds(),
+		})
+	}
+
+	sort.Slice(videos, func(i, j int) bool {
+		return videos[i].SecondsInStatus > videos[j].SecondsInStatus
+	})
+
+	videos, page := paginate(videos, limit, offset)
+
+	c.JSON(http.StatusOK, gin.H{
+		"videos":     videos,
+		"count":      len(videos),
+		"pagination": page,
 	})
 }
 
 // GetVideoHandler returns a specific video record
 func GetVideoHandler(c *gin.Context) {
-	id := c.Param("id")
-	record, exists := videoStorage.GetRecord(id)
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
 	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Video record not found",
-		})
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
 		return
 	}
 
@@ -78,49 +209,85 @@ func GetVideoHandler(c *gin.Context) {
 	})
 }
 
-// DeleteVideoHandler archives a video record (moves to history)
+// DeleteVideoHandler archives a video record (moves to history), or
+// permanently deletes it and its files when purge=true is given
 func DeleteVideoHandler(c *gin.Context) {
-	id := c.Param("id")
+	id := videoIDParam(c)
+	if _, exists := getVisibleRecord(c, id); !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
 
-	if err := videoStorage.DeleteRecord(id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Video record not found",
+	if c.Query("purge") == "true" {
+		if err := videoStorage.PurgeRecord(id); err != nil {
+			respondStorageError(c, err, "Failed to purge video")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Video and its files deleted permanently",
+			"id":      id,
 		})
 		return
 	}
 
+	if err := videoStorage.DeleteRecord(id); err != nil {
+		respondStorageError(c, err, "Failed to archive video")
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Video moved to history successfully",
 		"id":      id,
 	})
 }
 
+// ArchiveVideoHandler archives an active video record (moves to history)
+func ArchiveVideoHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	if record.IsArchived {
+		respondError(c, http.StatusBadRequest, ErrCodeVideoAlreadyArchived, "Video is already archived")
+		return
+	}
+
+	if err := videoStorage.DeleteRecord(id); err != nil {
+		respondStorageError(c, err, "Failed to archive video")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Video archived successfully",
+		"id":      id,
+	})
+}
+
 // RestoreVideoHandler restores an archived video record
 func RestoreVideoHandler(c *gin.Context) {
-	id := c.Param("id")
-	record, exists := videoStorage.GetRecord(id)
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
 	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Video record not found",
-		})
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
 		return
 	}
 
 	if !record.IsArchived {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Video is not archived",
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeVideoNotArchived, "Video is not archived")
 		return
 	}
 
-	// Restore the record
-	record.IsArchived = false
-	record.LastAccessed = time.Now()
+	// Restore a copy, the stored record may be read concurrently
+	updated := *record
+	updated.IsArchived = false
+	updated.LastAccessed = time.Now()
 
-	if err := videoStorage.UpdateRecord(record); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to restore video",
-		})
+	if err := videoStorage.UpdateRecord(&updated); err != nil {
+		respondStorageError(c, err, "Failed to restore video")
 		return
 	}
 
@@ -143,37 +310,221 @@ func CleanupOldVideosHandler(c *gin.Context) {
 	daysStr := c.DefaultQuery("days", "30")
 	days, err := strconv.Atoi(daysStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid days parameter",
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "Invalid days parameter")
 		return
 	}
 
 	if days < 7 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Minimum cleanup period is 7 days",
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "Minimum cleanup period is 7 days")
 		return
 	}
 
-	if err := videoStorage.CleanupOldRecords(days); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to cleanup old records",
-		})
+	removed, err := videoStorage.CleanupOldRecords(days)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to cleanup old records")
+		return
+	}
+
+	// Old searches and their stored images are cleaned up with the same threshold
+	searchesRemoved := 0
+	if searchHistory != nil {
+		searchesRemoved, err = searchHistory.CleanupOldRecords(days)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to cleanup old searches")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Cleanup completed successfully",
+		"days":             days,
+		"removed":          removed,
+		"searches_removed": searchesRemoved,
+	})
+}
+
+// ListLocationsHandler returns the distinct location names of all videos with
+// their video counts, e.g. to populate a location filter
+func ListLocationsHandler(c *gin.Context) {
+	locations := videoStorage.ListLocations(middleware.HasRestrictedAccess(c))
+	c.JSON(http.StatusOK, gin.H{
+		"locations": locations,
+		"count":     len(locations),
+	})
+}
+
+// GetFacesByLocationHandler returns the number of unique faces found at each
+// location, most first
+func GetFacesByLocationHandler(c *gin.Context) {
+	locations := videoStorage.ListFacesByLocation(middleware.HasRestrictedAccess(c))
+
+	totalFaces := 0
+	for _, location := range locations {
+		totalFaces += location.FaceCount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"locations":   locations,
+		"count":       len(locations),
+		"total_faces": totalFaces,
+	})
+}
+
+// PersonSighting is a unique face detected in a video, with where and when the video was recorded
+type PersonSighting struct {
+	Face         string    `json:"face"`
+	FaceURL      string    `json:"face_url"`
+	VideoID      string    `json:"video_id"`
+	UploadTime   time.Time `json:"upload_time"`
+	LocationName string    `json:"location_name,omitempty"`
+	Latitude     float64   `json:"latitude,omitempty"`
+	Longitude    float64   `json:"longitude,omitempty"`
+	DistanceKm   *float64  `json:"distance_km,omitempty"`
+}
+
+// parseTimeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// ListPersonsHandler returns the faces of the people seen in completed videos
+// within a time window and, optionally, near a point: "who was here then"
+func ListPersonsHandler(c *gin.Context) {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+
+	var from, to time.Time
+	if fromStr := c.Query("from"); fromStr != "" {
+		if from, err = parseTimeParam(fromStr); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "from must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+			return
+		}
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		if to, err = parseTimeParam(toStr); err != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "to must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+			return
+		}
+		// A bare date includes the whole day
+		if len(toStr) == len("2006-01-02") {
+			to = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "to must not be before from")
 		return
 	}
 
+	// near is "lat,lon,radius_km"
+	nearStr := c.Query("near")
+	var lat, lon, radiusKm float64
+	if nearStr != "" {
+		parts := strings.Split(nearStr, ",")
+		valid := len(parts) == 3
+		if valid {
+			var errLat, errLon, errRadius error
+			lat, errLat = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+			lon, errLon = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			radiusKm, errRadius = strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+			valid = errLat == nil && errLon == nil && errRadius == nil &&
+				lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180 && radiusKm > 0
+		}
+		if !valid {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "near must be lat,lon,radius_km with valid coordinates and a positive radius")
+			return
+		}
+	}
+
+	persons := []PersonSighting{}
+	for _, record := range visibleRecords(c, videoStorage.ListRecords()) {
+		if record.Status != "completed" || len(record.FaceImages) == 0 {
+			continue
+		}
+		if (!from.IsZero() && record.UploadTime.Before(from)) || (!to.IsZero() && record.UploadTime.After(to)) {
+			continue
+		}
+
+		var distancePtr *float64
+		if nearStr != "" {
+			distance, ok := record.NearestDistanceKm(lat, lon)
+			if !ok || distance > radiusKm {
+				continue
+			}
+			distancePtr = &distance
+		}
+
+		for _, face := range record.FaceImages {
+			persons = append(persons, PersonSighting{
+				Face:         face,
+				FaceURL:      faceURL(face),
+				VideoID:      record.ID,
+				UploadTime:   record.UploadTime,
+				LocationName: record.LocationName,
+				Latitude:     record.Latitude,
+				Longitude:    record.Longitude,
+				DistanceKm:   distancePtr,
+			})
+		}
+	}
+
+	// Most recent sightings first
+	sort.SliceStable(persons, func(i, j int) bool {
+		return persons[i].UploadTime.After(persons[j].UploadTime)
+	})
+
+	persons, page := paginate(persons, limit, offset)
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Cleanup completed successfully",
-		"days":    days,
+		"persons":    persons,
+		"count":      len(persons),
+		"pagination": page,
 	})
 }
 
-// SearchVideosHandler searches videos by filename or status
+// VideoSearchResult is a video record annotated with its distance from the search point
+type VideoSearchResult struct {
+	*models.VideoRecord
+	DistanceKm *float64 `json:"distance_km,omitempty"`
+}
+
+// SearchVideosHandler searches videos by filename, location, status and proximity
 func SearchVideosHandler(c *gin.Context) {
 	query := c.Query("q")
 	status := c.Query("status")
 	archived := c.Query("archived")
+	latStr := c.Query("lat")
+	lonStr := c.Query("lon")
+	radiusStr := c.Query("radius_km")
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
+
+	// Geo filtering requires all three parameters together
+	geoFilter := latStr != "" || lonStr != "" || radiusStr != ""
+	var lat, lon, radiusKm float64
+	if geoFilter {
+		var errLat, errLon, errRadius error
+		lat, errLat = strconv.ParseFloat(latStr, 64)
+		lon, errLon = strconv.ParseFloat(lonStr, 64)
+		radiusKm, errRadius = strconv.ParseFloat(radiusStr, 64)
+		if errLat != nil || errLon != nil || errRadius != nil {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "lat, lon and radius_km must all be provided as numbers")
+			return
+		}
+		if lat < -90 || lat > 90 || lon < -180 || lon > 180 || radiusKm <= 0 {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "Invalid coordinates or radius")
+			return
+		}
+	}
 
 	var records []*models.VideoRecord
 
@@ -184,6 +535,8 @@ func SearchVideosHandler(c *gin.Context) {
 	} else {
 		records = videoStorage.ListRecords()
 	}
+	records = visibleRecords(c, records)
+	sortNewestFirst(records)
 
 	// Filter by query if provided
 	if query != "" {
@@ -191,7 +544,8 @@ func SearchVideosHandler(c *gin.Context) {
 		for _, record := range records {
 			if contains(record.OriginalFilename, query) ||
 				contains(record.Status, query) ||
-				contains(record.ID, query) {
+				contains(record.ID, query) ||
+				contains(record.LocationName, query) {
 				filtered = append(filtered, record)
 			}
 		}
@@ -209,13 +563,44 @@ func SearchVideosHandler(c *gin.Context) {
 		records = filtered
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"videos":   records,
-		"count":    len(records),
-		"query":    query,
-		"status":   status,
-		"archived": archived,
-	})
+	results := []VideoSearchResult{}
+	for _, record := range records {
+		if !geoFilter {
+			results = append(results, VideoSearchResult{VideoRecord: record})
+			continue
+		}
+
+		// Filter by distance from the search point, including any location segments
+		distance, ok := record.NearestDistanceKm(lat, lon)
+		if ok && distance <= radiusKm {
+			results = append(results, VideoSearchResult{VideoRecord: record, DistanceKm: &distance})
+		}
+	}
+
+	// Nearest videos first when searching by location
+	if geoFilter {
+		sort.Slice(results, func(i, j int) bool {
+			return *results[i].DistanceKm < *results[j].DistanceKm
+		})
+	}
+
+	results, page := paginate(results, limit, offset)
+
+	response := gin.H{
+		"videos":     results,
+		"count":      len(results),
+		"pagination": page,
+		"query":      query,
+		"status":     status,
+		"archived":   archived,
+	}
+	if geoFilter {
+		response["lat"] = lat
+		response["lon"] = lon
+		response["radius_km"] = radiusKm
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // contains checks if a string contains a substring (case-insensitive)
@@ -238,6 +623,22 @@ func containsSubstring(s, substr string) bool {
 	return false
 }
 
+// ReindexStorageHandler reconciles the video records with the files on disk,
+// correcting face counts and reporting missing and orphaned files
+func ReindexStorageHandler(c *gin.Context) {
+	report, err := videoStorage.Reindex(videosDir, facesDir)
+	if err != nil {
+		respondStorageError(c, err, "Failed to reindex storage")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Reindex completed, %d record(s) updated", len(report.UpdatedRecords)),
+		"report":  report,
+		"stats":   videoStorage.GetStats(),
+	})
+}
+
 // ResetDatabaseHandler completely resets the database and removes all files
 func ResetDatabaseHandler(c *gin.Context) {
 	// Get confirmation from request - check both form data and query parameters
@@ -247,17 +648,13 @@ func ResetDatabaseHandler(c *gin.Context) {
 	}
 
 	if confirm != "true" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Please confirm by sending 'confirm=true'",
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeConfirmationRequired, "Please confirm by sending 'confirm=true'")
 		return
 	}
 
 	// Reset the storage
 	if err := videoStorage.ResetDatabase(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to reset database: " + err.Error(),
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to reset database: "+err.Error())
 		return
 	}
 
@@ -266,28 +663,72 @@ func ResetDatabaseHandler(c *gin.Context) {
 	})
 }
 
-// GetSearchHistoryHandler returns search history records
+// GetSearchHistoryHandler returns search history records, optionally only
+// those of one investigation
 func GetSearchHistoryHandler(c *gin.Context) {
 	if searchHistory == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Search history not initialized",
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeSearchHistoryUnavailable, "Search history not initialized")
+		return
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
 		return
 	}
 
 	records := searchHistory.ListRecords()
+
+	// Filter by investigation if requested
+	caseID := c.Query("case_id")
+	if caseID != "" {
+		filtered := []*models.SearchRecord{}
+		for _, record := range records {
+			if record.CaseID == caseID {
+				filtered = append(filtered, record)
+			}
+		}
+		records = filtered
+	}
+
+	records, page := paginate(records, limit, offset)
+
 	c.JSON(http.StatusOK, gin.H{
-		"searches": records,
-		"count":    len(records),
+		"searches":   records,
+		"count":      len(records),
+		"pagination": page,
+		"case_id":    caseID,
 	})
 }
 
+// GetSearchImageHandler serves the image used for a past search
+func GetSearchImageHandler(c *gin.Context) {
+	if searchHistory == nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeSearchHistoryUnavailable, "Search history not initialized")
+		return
+	}
+
+	searchID := c.Param("id")
+	middleware.SetLogField(c, "search_id", searchID)
+
+	record, exists := searchHistory.GetRecord(searchID)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeSearchNotFound, "Search record not found")
+		return
+	}
+
+	if !fileExists(record.SearchImagePath) {
+		respondError(c, http.StatusNotFound, ErrCodeSearchImageNotFound, "Search image not found")
+		return
+	}
+
+	c.File(record.SearchImagePath)
+}
+
 // GetSearchHistoryStatsHandler returns search history statistics
 func GetSearchHistoryStatsHandler(c *gin.Context) {
 	if searchHistory == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Search history not initialized",
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeSearchHistoryUnavailable, "Search history not initialized")
 		return
 	}
 
@@ -297,20 +738,16 @@ func GetSearchHistoryStatsHandler(c *gin.Context) {
 
 // GetVideoPreviewHandler returns video preview information
 func GetVideoPreviewHandler(c *gin.Context) {
-	id := c.Param("id")
-	record, exists := videoStorage.GetRecord(id)
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
 	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Video record not found",
-		})
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
 		return
 	}
 
 	// Check if video file exists
 	if _, err := os.Stat(record.StoredPath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Video file not found",
-		})
+		respondError(c, http.StatusNotFound, ErrCodeVideoFileNotFound, "Video file not found")
 		return
 	}
 
@@ -326,30 +763,604 @@ func GetVideoPreviewHandler(c *gin.Context) {
 			"longitude":         record.Longitude,
 			"unique_faces":      record.UniqueFacesCount,
 			"processing_time":   record.ProcessingTime,
-			"video_url":         fmt.Sprintf("/api/videos/%s/file", record.ID),
+			"rotation":          record.Rotation,
+			"video_url":         videoFileURL(record.ID),
 		},
 	})
 }
 
 // GetVideoFileHandler serves the actual video file
 func GetVideoFileHandler(c *gin.Context) {
-	id := c.Param("id")
-	record, exists := videoStorage.GetRecord(id)
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
 	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Video record not found",
-		})
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
 		return
 	}
 
 	// Check if video file exists
 	if _, err := os.Stat(record.StoredPath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Video file not found",
-		})
+		respondError(c, http.StatusNotFound, ErrCodeVideoFileNotFound, "Video file not found")
 		return
 	}
 
+	// Optionally re-hash the file before serving it; off by default since
+	// hashing large videos on every request is expensive
+	if c.Query("verify") == "true" {
+		if record.Checksum == "" {
+			respondError(c, http.StatusUnprocessableEntity, ErrCodeChecksumUnavailable, "No checksum recorded for this video")
+			return
+		}
+
+		actual, err := fileChecksum(record.StoredPath)
+		if err != nil {
+			log.Printf("Failed to hash video file %s: %v", record.StoredPath, err)
+			respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to read video file")
+			return
+		}
+		if actual != record.Checksum {
+			log.Printf("Checksum mismatch for video %s: expected %s, got %s", record.ID, record.Checksum, actual)
+			respondError(c, http.StatusConflict, ErrCodeChecksumMismatch, "Video file does not match its recorded checksum")
+			return
+		}
+	}
+
+	// Offer the file as a download named after the original upload
+	if c.Query("download") == "true" {
+		ext := strings.TrimPrefix(filepath.Ext(record.StoredPath), ".")
+		setAttachment(c, ext, record.ID, filenameStem(record.OriginalFilename))
+	}
+
 	// Serve the video file
 	c.File(record.StoredPath)
 }
+
+// VerifyVideoHandler re-hashes a stored video file and compares it with the
+// checksum recorded at upload to detect corruption or tampering
+func VerifyVideoHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	if record.Checksum == "" {
+		respondError(c, http.StatusUnprocessableEntity, ErrCodeChecksumUnavailable, "No checksum recorded for this video")
+		return
+	}
+
+	if _, err := os.Stat(record.StoredPath); os.IsNotExist(err) {
+		respondError(c, http.StatusNotFound, ErrCodeVideoFileNotFound, "Video file not found")
+		return
+	}
+
+	actual, err := fileChecksum(record.StoredPath)
+	if err != nil {
+		log.Printf("Failed to hash video file %s: %v", record.StoredPath, err)
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to read video file")
+		return
+	}
+
+	valid := actual == record.Checksum
+	if !valid {
+		log.Printf("Checksum mismatch for video %s: expected %s, got %s", record.ID, record.Checksum, actual)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":              record.ID,
+		"valid":           valid,
+		"checksum":        record.Checksum,
+		"actual_checksum": actual,
+	})
+}
+
+// PatchVideoRequest lists the mutable fields of a video record; only the
+// fields present in the request body are applied
+type PatchVideoRequest struct {
+	Status       *string   `json:"status"`
+	LocationName *string   `json:"location_name"`
+	Latitude     *float64  `json:"latitude"`
+	Longitude    *float64  `json:"longitude"`
+	Tags         *[]string `json:"tags"`
+	Notes        *string   `json:"notes"`
+	Visibility   *string   `json:"visibility"`
+}
+
+// validVideoStatuses are the statuses a video record may be set to
+var validVideoStatuses = map[string]bool{
+	"processing": true,
+	"completed":  true,
+	"failed":     true,
+	"rejected":   true,
+}
+
+// validVisibilities are the visibility levels a video record may be set to
+var validVisibilities = map[string]bool{
+	models.VisibilityPublic:     true,
+	models.VisibilityRestricted: true,
+}
+
+// PatchVideoHandler partially updates a video record, leaving fields that are
+// not in the request untouched
+func PatchVideoHandler(c *gin.Context) {
+	id := videoIDParam(c)
+
+	var request PatchVideoRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	// Validate every provided field before changing anything, reporting all problems at once
+	var fieldErrors []FieldError
+	if request.Status != nil && !validVideoStatuses[*request.Status] {
+		fieldErrors = append(fieldErrors, FieldError{Field: "status", Rule: "oneof", Message: "status must be one of processing, completed, failed, rejected"})
+	}
+	if request.Latitude != nil && (*request.Latitude < -90 || *request.Latitude > 90) {
+		fieldErrors = append(fieldErrors, FieldError{Field: "latitude", Rule: "range", Message: "latitude must be between -90 and 90"})
+	}
+	if request.Longitude != nil && (*request.Longitude < -180 || *request.Longitude > 180) {
+		fieldErrors = append(fieldErrors, FieldError{Field: "longitude", Rule: "range", Message: "longitude must be between -180 and 180"})
+	}
+	if request.Visibility != nil && !validVisibilities[*request.Visibility] {
+		fieldErrors = append(fieldErrors, FieldError{Field: "visibility", Rule: "oneof", Message: "visibility must be public or restricted"})
+	}
+	var tags []string
+	if request.Tags != nil {
+		for i, tag := range *request.Tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				fieldErrors = append(fieldErrors, FieldError{Field: fmt.Sprintf("tags[%d]", i), Rule: "required", Message: "tags must not be empty"})
+				continue
+			}
+			tags = append(tags, tag)
+		}
+	}
+	if len(fieldErrors) > 0 {
+		respondValidationErrors(c, fieldErrors)
+		return
+	}
+
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	// Update a copy so readers of the stored record never see it half-changed
+	updated := *record
+	if request.Status != nil && *request.Status != record.Status {
+		updated.Status = *request.Status
+		updated.StatusUpdatedAt = time.Now()
+	}
+	if request.LocationName != nil {
+		updated.LocationName = strings.TrimSpace(*request.LocationName)
+	}
+	if request.Latitude != nil {
+		updated.Latitude = *request.Latitude
+	}
+	if request.Longitude != nil {
+		updated.Longitude = *request.Longitude
+	}
+	if request.Tags != nil {
+		updated.Tags = tags
+	}
+	if request.Notes != nil {
+		updated.Notes = *request.Notes
+	}
+	if request.Visibility != nil {
+		updated.Visibility = *request.Visibility
+	}
+
+	if err := videoStorage.UpdateRecord(&updated); err != nil {
+		respondStorageError(c, err, "Failed to update video")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Video updated successfully",
+		"video":   &updated,
+	})
+}
+
+// ReviewVideoRequest marks a video as reviewed, or clears the mark when
+// Reviewed is false. There are no user accounts, so the client names the
+// reviewer.
+type ReviewVideoRequest struct {
+	Reviewed   *bool  `json:"reviewed"` // Defaults to true
+	ReviewedBy string `json:"reviewed_by"`
+}
+
+// maxReviewerLength bounds the reviewer name stored on a video
+const maxReviewerLength = 100
+
+// ReviewVideoHandler records that an operator has checked a video's footage
+func ReviewVideoHandler(c *gin.Context) {
+	id := videoIDParam(c)
+
+	var request ReviewVideoRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	reviewed := request.Reviewed == nil || *request.Reviewed
+	reviewer := strings.TrimSpace(request.ReviewedBy)
+
+	if reviewed && reviewer == "" {
+		respondValidationErrors(c, []FieldError{{Field: "reviewed_by", Rule: "required", Message: "reviewed_by must name the reviewer"}})
+		return
+	}
+	if len(reviewer) > maxReviewerLength {
+		respondValidationErrors(c, []FieldError{{Field: "reviewed_by", Rule: "max", Message: fmt.Sprintf("reviewed_by must be at most %d characters", maxReviewerLength)}})
+		return
+	}
+
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	// Update a copy so readers of the stored record never see it half-changed
+	updated := *record
+	updated.Reviewed = reviewed
+	updated.ReviewedBy = ""
+	updated.ReviewedAt = nil
+	if reviewed {
+		now := time.Now()
+		updated.ReviewedBy = reviewer
+		updated.ReviewedAt = &now
+	}
+
+	if err := videoStorage.UpdateRecord(&updated); err != nil {
+		respondStorageError(c, err, "Failed to update video")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Video review updated successfully",
+		"video":   &updated,
+	})
+}
+
+// maxBatchGetIDs bounds the number of videos fetched in one batch request
+const maxBatchGetIDs = 100
+
+// BatchGetVideosRequest is the body of a batch video lookup
+type BatchGetVideosRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// BatchGetVideosHandler returns several video records in one call, along
+// with the requested IDs that were not found. Restricted videos the caller
+// may not see are reported as missing.
+func BatchGetVideosHandler(c *gin.Context) {
+	var request BatchGetVideosRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if len(request.IDs) == 0 {
+		respondValidationErrors(c, []FieldError{{Field: "ids", Rule: "required", Message: "ids must contain at least one video ID"}})
+		return
+	}
+	if len(request.IDs) > maxBatchGetIDs {
+		respondValidationErrors(c, []FieldError{{Field: "ids", Rule: "max", Message: fmt.Sprintf("ids must not contain more than %d video IDs", maxBatchGetIDs)}})
+		return
+	}
+
+	// Look each ID up once, keeping the order of the request
+	seen := make(map[string]bool, len(request.IDs))
+	ids := make([]string, 0, len(request.IDs))
+	for _, id := range request.IDs {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	found, missing := videoStorage.LookupRecords(ids)
+	videos := visibleRecords(c, found)
+	if len(videos) < len(found) {
+		visible := make(map[string]bool, len(videos))
+		for _, record := range videos {
+			visible[record.ID] = true
+		}
+		for _, record := range found {
+			if !visible[record.ID] {
+				missing = append(missing, record.ID)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"videos":  videos,
+		"missing": missing,
+		"count":   len(videos),
+	})
+}
+
+// UpdateLocationRequest is the body for replacing a video's main location;
+// omitting both coordinates clears them
+type UpdateLocationRequest struct {
+	LocationName string   `json:"location_name"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+}
+
+// SetVideoLocationHandler corrects the location of a video after upload
+func SetVideoLocationHandler(c *gin.Context) {
+	id := videoIDParam(c)
+
+	var request UpdateLocationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if (request.Latitude == nil) != (request.Longitude == nil) {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "latitude and longitude must be provided together")
+		return
+	}
+
+	var latitude, longitude float64
+	if request.Latitude != nil {
+		latitude, longitude = *request.Latitude, *request.Longitude
+		if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "Invalid coordinates")
+			return
+		}
+	}
+
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	// Update a copy so readers of the stored record never see it half-changed
+	updated := *record
+	updated.LocationName = strings.TrimSpace(request.LocationName)
+	updated.Latitude = latitude
+	updated.Longitude = longitude
+
+	if err := videoStorage.UpdateRecord(&updated); err != nil {
+		respondStorageError(c, err, "Failed to update video location")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Video location updated successfully",
+		"id":            id,
+		"location_name": updated.LocationName,
+		"latitude":      updated.Latitude,
+		"longitude":     updated.Longitude,
+	})
+}
+
+// UpdateLocationSegmentsRequest is the body for replacing a video's location segments
+type UpdateLocationSegmentsRequest struct {
+	Segments []models.LocationSegment `json:"segments"`
+}
+
+// GetVideoLocationsHandler returns the location segments of a video
+func GetVideoLocationsHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	segments := record.LocationSegments
+	if segments == nil {
+		segments = []models.LocationSegment{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":            record.ID,
+		"location_name": record.LocationName,
+		"latitude":      record.Latitude,
+		"longitude":     record.Longitude,
+		"segments":      segments,
+	})
+}
+
+// GetVideoGeoJSONHandler returns a video's locations and detection results as
+// a GeoJSON FeatureCollection that map libraries can consume directly
+func GetVideoGeoJSONHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	collection, ok := record.GeoJSON()
+	if !ok {
+		respondError(c, http.StatusUnprocessableEntity, ErrCodeVideoLocationMissing, "Video has no coordinates")
+		return
+	}
+
+	c.Header("Content-Type", "application/geo+json")
+	c.JSON(http.StatusOK, collection)
+}
+
+// SetVideoLocationsHandler replaces the location segments of a video
+func SetVideoLocationsHandler(c *gin.Context) {
+	id := videoIDParam(c)
+
+	var request UpdateLocationSegmentsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	var fieldErrors []FieldError
+	for i, segment := range request.Segments {
+		field := fmt.Sprintf("segments[%d]", i)
+		if segment.StartSeconds < 0 {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".start_seconds", Rule: "min", Message: fmt.Sprintf("Segment %d: start_seconds must not be negative", i)})
+		}
+		if segment.EndSeconds <= segment.StartSeconds {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".end_seconds", Rule: "gtfield", Message: fmt.Sprintf("Segment %d: end_seconds must be greater than start_seconds", i)})
+		}
+		if segment.Latitude < -90 || segment.Latitude > 90 {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".latitude", Rule: "range", Message: fmt.Sprintf("Segment %d: latitude must be between -90 and 90", i)})
+		}
+		if segment.Longitude < -180 || segment.Longitude > 180 {
+			fieldErrors = append(fieldErrors, FieldError{Field: field + ".longitude", Rule: "range", Message: fmt.Sprintf("Segment %d: longitude must be between -180 and 180", i)})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		respondValidationErrors(c, fieldErrors)
+		return
+	}
+
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	if request.Segments == nil {
+		request.Segments = []models.LocationSegment{}
+	}
+
+	// Keep segments in playback order
+	sort.Slice(request.Segments, func(i, j int) bool {
+		return request.Segments[i].StartSeconds < request.Segments[j].StartSeconds
+	})
+
+	// Update a copy so readers of the stored record never see it half-changed
+	updated := *record
+	updated.LocationSegments = request.Segments
+
+	if err := videoStorage.UpdateRecord(&updated); err != nil {
+		respondStorageError(c, err, "Failed to update video locations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Video locations updated successfully",
+		"id":       id,
+		"segments": updated.LocationSegments,
+	})
+}
+
+// maxFailureOutputSize caps the output returned for a failed run; the end of
+// the output, with the traceback, is kept
+const maxFailureOutputSize = 64 * 1024
+
+// GetVideoFailureDetailsHandler returns the full output of the last face
+// detection run of a failed or rejected video, including the Python
+// traceback that ErrorMessage leaves out. Local paths are redacted, and the
+// restricted scope is required since the output describes the server.
+func GetVideoFailureDetailsHandler(c *gin.Context) {
+	if !middleware.HasRestrictedAccess(c) {
+		respondError(c, http.StatusForbidden, ErrCodeRestrictedAccess, "Failure details require the restricted access scope")
+		return
+	}
+
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+	if record.Status != "failed" && record.Status != "rejected" {
+		respondError(c, http.StatusConflict, ErrCodeVideoNotFailed, fmt.Sprintf("Video processing has not failed (status: %s)", record.Status))
+		return
+	}
+
+	data, err := os.ReadFile(models.ProcessingLogPath(record.ID))
+	if os.IsNotExist(err) {
+		respondError(c, http.StatusNotFound, ErrCodeProcessingLogNotFound, "No processing log recorded for this video")
+		return
+	}
+	if err != nil {
+		log.Printf("Error reading processing log of %s: %v", record.ID, err)
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to read processing log")
+		return
+	}
+
+	// Only the last run failed for good; earlier ones were retried
+	output := string(data)
+	runHeader := ""
+	if i := strings.LastIndex(output, "=== Processing run at "); i >= 0 {
+		output = output[i:]
+		runHeader, output, _ = strings.Cut(output, "\n")
+	}
+
+	truncated := false
+	if len(output) > maxFailureOutputSize {
+		output = output[len(output)-maxFailureOutputSize:]
+		if i := strings.IndexByte(output, '\n'); i >= 0 {
+			output = output[i+1:]
+		}
+		truncated = true
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"video_id":      record.ID,
+		"status":        record.Status,
+		"error_message": record.ErrorMessage,
+		"run":           strings.Trim(runHeader, "= "),
+		"output":        redactLocalPaths(output),
+		"truncated":     truncated,
+	})
+}
+
+// redactLocalPaths replaces the server's directories in script output with
+// placeholders so responses do not reveal the deployment layout
+func redactLocalPaths(output string) string {
+	var replacements []string
+	if storageDir, err := filepath.Abs("../storage"); err == nil {
+		replacements = append(replacements, storageDir, "<storage>")
+	}
+	if apiDir, err := os.Getwd(); err == nil {
+		replacements = append(replacements, apiDir, "<api>")
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil && homeDir != "/" {
+		replacements = append(replacements, homeDir, "~")
+	}
+	return strings.NewReplacer(replacements...).Replace(output)
+}
+
+// GetVideoLogsHandler returns the output of the face detection runs of a
+// video, kept after processing so failed runs can be debugged
+func GetVideoLogsHandler(c *gin.Context) {
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	data, err := os.ReadFile(models.ProcessingLogPath(record.ID))
+	if os.IsNotExist(err) {
+		respondError(c, http.StatusNotFound, ErrCodeProcessingLogNotFound, "No processing log recorded for this video")
+		return
+	}
+	if err != nil {
+		log.Printf("Error reading processing log of %s: %v", record.ID, err)
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to read processing log")
+		return
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	truncated := len(lines) > 0 && lines[0] == models.ProcessingLogTruncatedMarker
+	if truncated {
+		lines = lines[1:]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"video_id":  record.ID,
+		"status":    record.Status,
+		"lines":     lines,
+		"truncated": truncated,
+	})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReviewVideo(t *testing.T) {
+	storage := useTestStorage(t)
+	for _, id := range []string{"video_1", "video_2"} {
+		if err := storage.AddRecord(&models.VideoRecord{ID: id, Status: "completed"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := gin.New()
+	r.GET("/videos", ListVideosHandler)
+	r.POST("/videos/:id/review", ReviewVideoHandler)
+
+	review := func(id, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/videos/"+id+"/review", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	listIDs := func(query string) []string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/videos?"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("list %q: status %d: %s", query, w.Code, w.Body.String())
+		}
+		var response struct {
+			Videos []models.VideoRecord `json:"videos"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, video := range response.Videos {
+			ids = append(ids, video.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		body     string
+		wantCode int
+	}{
+		{"missing reviewer", "video_1", `{}`, http.StatusBadRequest},
+		{"blank reviewer", "video_1", `{"reviewed_by": "  "}`, http.StatusBadRequest},
+		{"reviewer too long", "video_1", `{"reviewed_by": "` + strings.Repeat("a", maxReviewerLength+1) + `"}`, http.StatusBadRequest},
+		{"unknown video", "video_9", `{"reviewed_by": "operator-7"}`, http.StatusNotFound},
+		{"mark reviewed", "video_1", `{"reviewed_by": " operator-7 "}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := review(tt.id, tt.body); w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+		})
+	}
+
+	record := storage.Records["video_1"]
+	if !record.Reviewed || record.ReviewedBy != "operator-7" || record.ReviewedAt == nil {
+		t.Errorf("stored review = %v by %q at %v, want reviewed by operator-7 with a time", record.Reviewed, record.ReviewedBy, record.ReviewedAt)
+	}
+
+	if ids := listIDs("reviewed=true"); strings.Join(ids, ",") != "video_1" {
+		t.Errorf("reviewed=true lists %v, want [video_1]", ids)
+	}
+	if ids := listIDs("reviewed=false"); strings.Join(ids, ",") != "video_2" {
+		t.Errorf("reviewed=false lists %v, want [video_2]", ids)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/videos?reviewed=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("reviewed=maybe: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// Clearing the review needs no reviewer and removes the attribution
+	if w := review("video_1", `{"reviewed": false}`); w.Code != http.StatusOK {
+		t.Fatalf("clear review: status %d: %s", w.Code, w.Body.String())
+	}
+	record = storage.Records["video_1"]
+	if record.Reviewed || record.ReviewedBy != "" || record.ReviewedAt != nil {
+		t.Errorf("cleared review = %v by %q at %v, want no review", record.Reviewed, record.ReviewedBy, record.ReviewedAt)
+	}
+	if ids := listIDs("reviewed=true"); len(ids) != 0 {
+		t.Errorf("reviewed=true lists %v after clearing, want none", ids)
+	}
+}
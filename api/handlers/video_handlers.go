@@ -1,19 +1,27 @@
 package handlers
 
 import (
+	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"video-processing-backend/config"
+	"video-processing-backend/middleware"
 	"video-processing-backend/models"
 
 	"github.com/gin-gonic/gin"
@@ -21,25 +29,45 @@ import (
 
 var searchHistory *models.SearchHistory
 
+// videosDir is where uploaded videos are stored
+const videosDir = "../storage/videos"
+
+// searchesDir is where search images are kept for the search history
+const searchesDir = "../storage/searches"
+
 // VideoUploadResponse represents the response structure
 type VideoUploadResponse struct {
-	UniqueFacesCount int      `json:"unique_faces_count"`
-	Faces            []string `json:"faces"`
-	Message          string   `json:"message"`
-	ProcessingTime   float64  `json:"processing_time_seconds"`
+	UniqueFacesCount int              `json:"unique_faces_count"`
+	Faces            []string         `json:"faces"`
+	FaceBoxes        []models.FaceBox `json:"face_boxes"`
+	Rotation         int              `json:"rotation"` // Display rotation in degrees clockwise
+	DurationSeconds  float64          `json:"duration_seconds"`
+	ModelVersion     string           `json:"model_version"`
+	Message          string           `json:"message"`
+	ProcessingTime   float64          `json:"processing_time_seconds"`
 }
 
 // FaceSearchResponse represents the face search response structure
 type FaceSearchResponse struct {
-	Matches []FaceMatch `json:"matches"`
-	Message string      `json:"message"`
+	SearchID     string      `json:"search_id,omitempty"`
+	CaseID       string      `json:"case_id,omitempty"`
+	VideoID      string      `json:"video_id,omitempty"` // The only video searched, when the search was limited to one
+	Matches      []FaceMatch `json:"matches"`
+	TotalMatches int         `json:"total_matches"` // Matches across all pages
+	Pagination   Pagination  `json:"pagination"`
+	Message      string      `json:"message"`
+	Partial      bool        `json:"partial"` // True when the time budget ran out before all videos were checked
+	ModelVersion string      `json:"model_version"`
+
+	DistanceMetric string `json:"distance_metric"` // How face encodings were compared
 }
 
 // FaceMatch represents a match found in a video
 type FaceMatch struct {
 	Video        *models.VideoRecord `json:"video"`
-	MatchedFaces []string            `json:"matched_faces"`
-	Similarity   float64             `json:"similarity"`
+	MatchedFaces []string            `json:"matched_faces"`         // Best crop of each distinct matched face
+	MatchCount   int                 `json:"match_count"`           // Number of stored faces that matched before deduplication
+	Similarity   float64             `json:"similarity" unit:"0-1"` // Highest similarity among the matched faces
 }
 
 // UploadVideoHandler handles video upload and processing
@@ -49,17 +77,35 @@ func UploadVideoHandler(c *gin.Context) {
 	// Get the uploaded file
 	file, err := c.FormFile("video")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No video file provided",
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeMissingFile, "No video file provided")
 		return
 	}
 
 	// Validate file type
 	if !isValidVideoFile(file.Filename) {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid video file format. Supported formats: mp4, avi, mov, mkv",
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidFileFormat, "Invalid video file format. Supported formats: "+formatExtensions(allowedVideoTypes))
+		return
+	}
+
+	// Reject re-uploads of a known video unless explicitly forced
+	storage := GetVideoStorage()
+	force := c.PostForm("force") == "true" || c.Query("force") == "true"
+	if !force {
+		if existing := storage.FindDuplicate(file.Filename, ""); existing != nil {
+			respondDuplicateVideo(c, existing)
+			return
+		}
+	}
+
+	visibility := c.DefaultPostForm("visibility", models.VisibilityPublic)
+	if !validVisibilities[visibility] {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "visibility must be public or restricted")
+		return
+	}
+
+	segment, err := parseVideoSegment(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
 		return
 	}
 
@@ -88,53 +134,112 @@ func UploadVideoHandler(c *gin.Context) {
 
 	// Create unique ID and filename
 	videoID := fmt.Sprintf("video_%d", time.Now().Unix())
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%d_%s", timestamp, filepath.Base(file.Filename))
-	videoPath := filepath.Join("../storage/videos", filename)
+	middleware.SetLogField(c, "video_id", videoID)
+	uploadTime := time.Now()
+	filename := fmt.Sprintf("%d_%s", uploadTime.Unix(), filepath.Base(file.Filename))
+	videoPath := filepath.Join(videoDirFor(uploadTime), filename)
 
 	// Create video record
 	videoRecord := &models.VideoRecord{
 		ID:               videoID,
 		OriginalFilename: file.Filename,
 		StoredPath:       videoPath,
-		UploadTime:       time.Now(),
+		UploadTime:       uploadTime,
 		Status:           "processing",
+		StatusUpdatedAt:  time.Now(),
 		LocationName:     locationName,
 		Latitude:         latitude,
 		Longitude:        longitude,
+		Visibility:       visibility,
+		SegmentStart:     segment.Start,
+		SegmentEnd:       segment.End,
 	}
 
 	// Save the uploaded file
+	if err := os.MkdirAll(filepath.Dir(videoPath), 0755); err != nil {
+		log.Printf("Error creating videos directory: %v", err)
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to create videos directory")
+		return
+	}
 	if err := c.SaveUploadedFile(file, videoPath); err != nil {
 		log.Printf("Error saving file: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save video file",
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to save video file")
 		return
 	}
 
+	// Record a checksum so the stored file can be verified later
+	checksum, err := fileChecksum(videoPath)
+	if err != nil {
+		log.Printf("Warning: Could not compute checksum for %s: %v", videoPath, err)
+	}
+	videoRecord.Checksum = checksum
+
+	// The same content may have been uploaded under another filename
+	if !force && checksum != "" {
+		if existing := storage.FindDuplicate("", checksum); existing != nil {
+			if err := os.Remove(videoPath); err != nil {
+				log.Printf("Warning: Could not remove duplicate upload %s: %v", videoPath, err)
+			}
+			respondDuplicateVideo(c, existing)
+			return
+		}
+	}
+
 	// Save record to storage
-	storage := GetVideoStorage()
 	if err := storage.AddRecord(videoRecord); err != nil {
 		log.Printf("Error saving video record: %v", err)
 	}
 
 	log.Printf("Video saved: %s (Location: %s, Lat: %f, Lon: %f)",
 		videoPath, locationName, latitude, longitude)
+	publishVideoActivity(ActivityUploadStarted, videoRecord, map[string]interface{}{
+		"filename": videoRecord.OriginalFilename,
+	})
 
 	// Process video with Python script
-	response, err := processVideoWithPython(videoPath, videoID)
+	response, err := processVideoWithPython(videoPath, videoID, segment)
+	var rejected *videoRejectedError
+	if errors.As(err, &rejected) {
+		log.Printf("Video %s rejected: %s", videoID, rejected.Reason)
+
+		// Drop the file so rejected videos don't take up storage
+		if err := os.Remove(videoPath); err != nil {
+			log.Printf("Warning: Could not remove rejected video %s: %v", videoPath, err)
+		}
+
+		// Storage owns videoRecord now and may be serializing it, so store a copy
+		rejectedRecord := *videoRecord
+		rejectedRecord.Status = "rejected"
+		rejectedRecord.StatusUpdatedAt = time.Now()
+		rejectedRecord.DurationSeconds = rejected.DurationSeconds
+		rejectedRecord.ErrorMessage = rejected.Reason
+		if err := storage.UpdateRecord(&rejectedRecord); err != nil {
+			log.Printf("Error updating video record %s: %v", videoID, err)
+		}
+		publishVideoActivity(ActivityAnalysisFailed, &rejectedRecord, map[string]interface{}{
+			"status": rejectedRecord.Status,
+			"reason": rejected.Reason,
+		})
+
+		respondError(c, http.StatusUnprocessableEntity, rejected.errorCode(), rejected.Reason)
+		return
+	}
 	if err != nil {
 		log.Printf("Error processing video: %v", err)
 
-		// Update record with error
-		videoRecord.Status = "failed"
-		videoRecord.ErrorMessage = err.Error()
-		storage.UpdateRecord(videoRecord)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process video",
+		// Update a copy of the record with the error
+		failedRecord := *videoRecord
+		failedRecord.Status = "failed"
+		failedRecord.StatusUpdatedAt = time.Now()
+		failedRecord.ErrorMessage = err.Error()
+		if err := storage.UpdateRecord(&failedRecord); err != nil {
+			log.Printf("Error updating video record %s: %v", videoID, err)
+		}
+		publishVideoActivity(ActivityAnalysisFailed, &failedRecord, map[string]interface{}{
+			"status": failedRecord.Status,
 		})
+
+		respondError(c, http.StatusInternalServerError, ErrCodeProcessingFailed, "Failed to process video")
 		return
 	}
 
@@ -142,96 +247,233 @@ func UploadVideoHandler(c *gin.Context) {
 	processingTime := time.Since(startTime).Seconds()
 	response.ProcessingTime = processingTime
 
-	// Update record with results
-	videoRecord.Status = "completed"
-	videoRecord.ProcessingTime = processingTime
-	videoRecord.UniqueFacesCount = response.UniqueFacesCount
-	videoRecord.FaceImages = response.Faces
-	storage.UpdateRecord(videoRecord)
+	// Update a copy of the record with the results
+	completedRecord := *videoRecord
+	completedRecord.Status = "completed"
+	completedRecord.StatusUpdatedAt = time.Now()
+	completedRecord.ProcessingTime = processingTime
+	completedRecord.UniqueFacesCount = response.UniqueFacesCount
+	completedRecord.FaceImages = response.Faces
+	completedRecord.FaceBoxes = response.FaceBoxes
+	completedRecord.Rotation = response.Rotation
+	completedRecord.DurationSeconds = response.DurationSeconds
+	completedRecord.ModelVersion = response.ModelVersion
+	if err := storage.UpdateRecord(&completedRecord); err != nil {
+		log.Printf("Error updating video record %s: %v", videoID, err)
+	}
+	publishVideoActivity(ActivityAnalysisCompleted, &completedRecord, map[string]interface{}{
+		"unique_faces_count":      completedRecord.UniqueFacesCount,
+		"processing_time_seconds": processingTime,
+	})
 
 	c.JSON(http.StatusOK, response)
 }
 
+// respondDuplicateVideo rejects an upload that matches an existing video,
+// pointing the client at the existing record
+func respondDuplicateVideo(c *gin.Context, existing *models.VideoRecord) {
+	// Do not reveal restricted videos to callers who may not see them
+	if existing.IsRestricted() && !middleware.HasRestrictedAccess(c) {
+		respondError(c, http.StatusConflict, ErrCodeDuplicateVideo, "A video with the same filename or content already exists. Send force=true to upload it anyway")
+		return
+	}
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error":       "A video with the same filename or content already exists. Send force=true to upload it anyway",
+		"code":        ErrCodeDuplicateVideo,
+		"existing_id": existing.ID,
+		"is_archived": existing.IsArchived,
+	})
+}
+
+// Orders in which face search matches can be returned
+const (
+	matchOrderSimilarity = "similarity"  // Best match first
+	matchOrderUploadTime = "upload_time" // Earliest video first
+)
+
+// sortFaceMatches orders search matches by the given order. Ties are broken
+// by video ID so pages are stable across requests.
+func sortFaceMatches(matches []FaceMatch, orderBy string) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case orderBy == matchOrderUploadTime && !a.Video.UploadTime.Equal(b.Video.UploadTime):
+			return a.Video.UploadTime.Before(b.Video.UploadTime)
+		case orderBy == matchOrderSimilarity && a.Similarity != b.Similarity:
+			return a.Similarity > b.Similarity
+		}
+		return a.Video.ID < b.Video.ID
+	})
+}
+
 // SearchByFaceHandler handles face search functionality
 func SearchByFaceHandler(c *gin.Context) {
+	startTime := time.Now()
+
 	// Get the uploaded search image
 	file, err := c.FormFile("search_image")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No search image provided",
-		})
+		respondError(c, http.StatusBadRequest, ErrCodeMissingFile, "No search image provided")
 		return
 	}
 
 	// Validate file type
-	if !isValidImageFile(file.Filename) {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid image file format. Supported formats: jpg, jpeg, png",
-		})
+	if err := validateImageUpload(file); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidFileFormat, err.Error())
+		return
+	}
+
+	// Optional order and page of the returned matches
+	orderBy := c.PostForm("order_by")
+	if orderBy == "" {
+		orderBy = c.DefaultQuery("order_by", matchOrderSimilarity)
+	}
+	if orderBy != matchOrderSimilarity && orderBy != matchOrderUploadTime {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, fmt.Sprintf("order_by must be %q or %q", matchOrderSimilarity, matchOrderUploadTime))
+		return
+	}
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
 		return
 	}
 
-	// Save the search image temporarily
-	searchImagePath := filepath.Join("../storage/temp", fmt.Sprintf("search_%d_%s", time.Now().Unix(), filepath.Base(file.Filename)))
+	metric := c.PostForm("distance_metric")
+	if metric == "" {
+		metric = c.Query("distance_metric")
+	}
+	if metric, err = parseDistanceMetric(metric); err != nil {
+		respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, err.Error())
+		return
+	}
 
-	// Create temp directory if it doesn't exist
+	// Optional investigation the search is filed under
+	caseID := strings.TrimSpace(c.PostForm("case_id"))
+
+	// Optionally search a single video, e.g. the camera an investigator suspects
+	videoID := strings.TrimSpace(c.PostForm("video_id"))
+	var targetVideo *models.VideoRecord
+	if videoID != "" {
+		record, exists := getVisibleRecord(c, videoID)
+		if !exists {
+			respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+			return
+		}
+		targetVideo = record
+	}
+
+	// Optional time budget for the search
+	ctx := c.Request.Context()
+	timeoutStr := c.PostForm("timeout_seconds")
+	if timeoutStr == "" {
+		timeoutStr = c.Query("timeout_seconds")
+	}
+	if timeoutStr != "" {
+		timeoutSeconds, err := strconv.ParseFloat(timeoutStr, 64)
+		if err != nil || timeoutSeconds <= 0 {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "timeout_seconds must be a positive number")
+			return
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutSeconds*float64(time.Second)))
+		defer cancel()
+	}
+
+	// Keep the search image so it can be shown in the search history
+	searchID := fmt.Sprintf("search_%d", time.Now().UnixNano())
+	middleware.SetLogField(c, "search_id", searchID)
+	middleware.SetLogField(c, "case_id", caseID)
+	middleware.SetLogField(c, "video_id", videoID)
+	searchImagePath := filepath.Join(searchesDir, searchID+strings.ToLower(filepath.Ext(file.Filename)))
+
+	// Create searches directory if it doesn't exist
 	if err := os.MkdirAll(filepath.Dir(searchImagePath), 0755); err != nil {
-		log.Printf("Error creating temp directory: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create temporary directory",
-		})
+		log.Printf("Error creating searches directory: %v", err)
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to create searches directory")
 		return
 	}
 
 	if err := c.SaveUploadedFile(file, searchImagePath); err != nil {
 		log.Printf("Error saving search image: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save search image",
-		})
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to save search image")
 		return
 	}
 
 	// Get all videos with faces
 	storage := GetVideoStorage()
-	allVideos := storage.ListRecords()
-
-	matches := []FaceMatch{} // Initialize as empty slice, not nil
+	allVideos := visibleRecords(c, storage.ListRecords())
+	if targetVideo != nil {
+		allVideos = []*models.VideoRecord{targetVideo}
+	}
 
-	// Search through each video's faces
-	log.Printf("Searching through %d videos", len(allVideos))
-	for _, video := range allVideos {
-		log.Printf("Checking video %s: status=%s, faces=%d", video.ID, video.Status, len(video.FaceImages))
-		if video.Status == "completed" && len(video.FaceImages) > 0 {
-			// Compare search image with faces in this video
-			matchedFaces, err := compareFacesWithSearchImage(searchImagePath, video.FaceImages)
-			if err != nil {
-				log.Printf("Error comparing faces for video %s: %v", video.ID, err)
-				continue
-			}
+	matches, partial := searchVideosForFace(ctx, searchImagePath, allVideos, metric)
 
-			log.Printf("Video %s: found %d matched faces", video.ID, len(matchedFaces))
-			if len(matchedFaces) > 0 {
-				matches = append(matches, FaceMatch{
-					Video:        video,
-					MatchedFaces: matchedFaces,
-					Similarity:   0.85, // Default similarity score
-				})
-			}
+	// Running out of the client's own time budget yields partial results, but
+	// the server's request timeout aborts the search
+	if partial && errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		log.Printf("Search %s exceeded the request timeout", searchID)
+		if err := os.Remove(searchImagePath); err != nil {
+			log.Printf("Warning: Could not remove search image %s: %v", searchImagePath, err)
 		}
+		respondError(c, http.StatusRequestTimeout, ErrCodeRequestTimeout, "Search timed out")
+		return
 	}
 
-	// Clean up temporary search image
-	defer os.Remove(searchImagePath)
-
 	// Add debug logging
-	log.Printf("Search completed. Found %d matches", len(matches))
+	log.Printf("Search completed. Found %d matches (partial: %t)", len(matches), partial)
 	for i, match := range matches {
 		log.Printf("Match %d: Video %s, %d matched faces", i+1, match.Video.ID, len(match.MatchedFaces))
 	}
 
+	// Record the search in history
+	matchedVideoIDs := []string{}
+	for _, match := range matches {
+		matchedVideoIDs = append(matchedVideoIDs, match.Video.ID)
+	}
+
+	searchRecord := &models.SearchRecord{
+		ID:              searchID,
+		CaseID:          caseID,
+		VideoID:         videoID,
+		SearchImagePath: searchImagePath,
+		SearchTime:      startTime,
+		QueryHash:       generateImageHash(searchImagePath),
+		MatchesFound:    len(matches),
+		TotalVideos:     len(allVideos),
+		MatchedVideos:   matchedVideoIDs,
+		ProcessingTime:  time.Since(startTime).Seconds(),
+		ModelVersion:    modelVersion,
+		DistanceMetric:  metric,
+	}
+	if searchHistory != nil {
+		if err := searchHistory.AddRecord(searchRecord); err != nil {
+			log.Printf("Error saving search record: %v", err)
+		}
+	}
+
+	publishSearchActivity(searchID, matches, len(allVideos), partial)
+
+	// The history and events cover every match, the response only the page asked for
+	sortFaceMatches(matches, orderBy)
+	page, pagination := paginate(matches, limit, offset)
+
 	response := FaceSearchResponse{
-		Matches: matches,
-		Message: fmt.Sprintf("Found %d video(s) with matching faces", len(matches)),
+		SearchID:     searchID,
+		CaseID:       caseID,
+		VideoID:      videoID,
+		Matches:      page,
+		TotalMatches: len(matches),
+		Pagination:   pagination,
+		Message:      fmt.Sprintf("Found %d video(s) with matching faces", len(matches)),
+		Partial:      partial,
+		ModelVersion: modelVersion,
+
+		DistanceMetric: metric,
+	}
+	if partial {
+		response.Message += " before the time budget ran out"
 	}
 
 	// Ensure matches is always an array, not null
@@ -246,16 +488,229 @@ func SearchByFaceHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// ExtendSearchHandler re-runs a past search against only the videos analyzed
+// since it last ran, e.g. after new footage arrived, and adds any new matches
+// to its history record
+func ExtendSearchHandler(c *gin.Context) {
+	if searchHistory == nil {
+		respondError(c, http.StatusInternalServerError, ErrCodeSearchHistoryUnavailable, "Search history not initialized")
+		return
+	}
+
+	searchID := c.Param("id")
+	middleware.SetLogField(c, "search_id", searchID)
+
+	record, exists := searchHistory.GetRecord(searchID)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeSearchNotFound, "Search record not found")
+		return
+	}
+	if !fileExists(record.SearchImagePath) {
+		respondError(c, http.StatusNotFound, ErrCodeSearchImageNotFound, "Search image not found")
+		return
+	}
+
+	startTime := time.Now()
+	since := record.SearchedUntil()
+
+	// Videos still being analyzed when the search ran were skipped then, so
+	// anything analyzed since counts as new. A search limited to one video
+	// never covers new videos.
+	alreadyMatched := make(map[string]bool, len(record.MatchedVideos))
+	for _, id := range record.MatchedVideos {
+		alreadyMatched[id] = true
+	}
+	newVideos := []*models.VideoRecord{}
+	if record.VideoID == "" {
+		for _, video := range visibleRecords(c, GetVideoStorage().ListRecords()) {
+			analyzedAt := video.StatusUpdatedAt
+			if analyzedAt.IsZero() {
+				analyzedAt = video.UploadTime
+			}
+			if analyzedAt.After(since) && !alreadyMatched[video.ID] {
+				newVideos = append(newVideos, video)
+			}
+		}
+	}
+
+	// Compare the same way as the original search; searches from before the
+	// metric was configurable used euclidean distance
+	metric := record.DistanceMetric
+	if metric == "" {
+		metric = distanceMetricEuclidean
+	}
+	matches, partial := searchVideosForFace(c.Request.Context(), record.SearchImagePath, newVideos, metric)
+	if partial {
+		log.Printf("Extending search %s exceeded the request timeout", searchID)
+		respondError(c, http.StatusRequestTimeout, ErrCodeRequestTimeout, "Search timed out")
+		return
+	}
+	sortFaceMatches(matches, matchOrderSimilarity)
+
+	// Update a copy so readers of the stored record never see it half-changed
+	updated := *record
+	updated.MatchedVideos = append([]string{}, record.MatchedVideos...)
+	for _, match := range matches {
+		updated.MatchedVideos = append(updated.MatchedVideos, match.Video.ID)
+	}
+	updated.MatchesFound = len(updated.MatchedVideos)
+	updated.TotalVideos += len(newVideos)
+	updated.ProcessingTime += time.Since(startTime).Seconds()
+	updated.ExtendedAt = &startTime
+	if err := searchHistory.UpdateRecord(&updated); err != nil {
+		log.Printf("Error updating search record %s: %v", searchID, err)
+		respondError(c, http.StatusInternalServerError, ErrCodeStorageError, "Failed to update search record")
+		return
+	}
+
+	publishSearchActivity(searchID, matches, len(newVideos), false)
+
+	c.JSON(http.StatusOK, gin.H{
+		"search_id":      searchID,
+		"searched_since": since,
+		"videos_scanned": len(newVideos),
+		"new_matches":    matches,
+		"message":        fmt.Sprintf("Found %d new video(s) with matching faces", len(matches)),
+		"search":         updated,
+	})
+}
+
+// searchVideosForFace compares the search image against the faces of each
+// completed video, up to searchConcurrency videos at a time. Matches are
+// returned in no particular order. If the context ends before all videos are
+// checked, the matches found so far are returned with partial set to true.
+func searchVideosForFace(ctx context.Context, searchImagePath string, videos []*models.VideoRecord, metric string) ([]FaceMatch, bool) {
+	matches := []FaceMatch{} // Initialize as empty slice, not nil
+	partial := false
+	var mu sync.Mutex // Guards matches and partial
+
+	var candidates []*models.VideoRecord
+	for _, video := range videos {
+		if video.Status == "completed" && len(video.FaceImages) > 0 {
+			candidates = append(candidates, video)
+		}
+	}
+	log.Printf("Searching through %d videos (%d with faces)", len(videos), len(candidates))
+
+	// Each video is compared by its own script run, so they run independently
+	jobs := make(chan *models.VideoRecord)
+	var wg sync.WaitGroup
+	for i := 0; i < min(searchConcurrency, len(candidates)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for video := range jobs {
+				matchedFaces, err := compareFacesWithSearchImage(ctx, searchImagePath, video.FaceImages, metric)
+				if err != nil {
+					if ctx.Err() != nil {
+						mu.Lock()
+						partial = true
+						mu.Unlock()
+					} else {
+						log.Printf("Error comparing faces for video %s: %v", video.ID, err)
+					}
+					continue
+				}
+
+				log.Printf("Video %s: found %d matched faces", video.ID, len(matchedFaces))
+				if len(matchedFaces) > 0 {
+					match := newFaceMatch(video, matchedFaces)
+					mu.Lock()
+					matches = append(matches, match)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, video := range candidates {
+		select {
+		case jobs <- video:
+		case <-ctx.Done():
+			mu.Lock()
+			partial = true
+			mu.Unlock()
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return matches, partial
+}
+
 // HealthCheckHandler provides a simple health check endpoint
 func HealthCheckHandler(c *gin.Context) {
+	// Data lost to a corrupt storage file needs an operator even though the
+	// server keeps running
+	if incidents := readiness.incidents(); len(incidents) > 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"status":            "degraded",
+			"storage_incidents": incidents,
+			"timestamp":         time.Now().Unix(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "healthy",
 		"timestamp": time.Now().Unix(),
 	})
 }
 
-// processVideoWithPython calls the Python script to process the video
-func processVideoWithPython(videoPath string, videoID string) (*VideoUploadResponse, error) {
+// processVideoWithPython calls the Python script to process the video.
+// Transient failures are retried with exponential backoff; permanent ones,
+// such as unreadable or rejected videos, are returned immediately.
+func processVideoWithPython(videoPath string, videoID string, segment videoSegment) (*VideoUploadResponse, error) {
+	// Let clients follow the output of every attempt
+	processingLogs.start(videoID)
+	defer processingLogs.finish(videoID)
+
+	for attempt := 1; ; attempt++ {
+		response, err := runFaceDetection(videoPath, videoID, segment)
+		if err == nil || !errors.Is(err, errTransientFailure) || attempt > processingMaxRetries {
+			return response, err
+		}
+
+		delay := processingRetryBackoff << (attempt - 1)
+		log.Printf("Face detection attempt %d for %s failed, retrying in %s: %v", attempt, videoID, delay, err)
+		time.Sleep(delay)
+	}
+}
+
+// errTransientFailure marks Python failures that may succeed when retried
+var errTransientFailure = errors.New("transient failure")
+
+// transientFailurePatterns are output fragments of failures worth retrying,
+// such as running out of (GPU) memory; anything else is treated as permanent
+var transientFailurePatterns = []string{
+	"out of memory",
+	"memoryerror",
+	"cuda error",
+	"resource temporarily unavailable",
+}
+
+// isTransientFailure reports whether a failed Python run is worth retrying:
+// the process was killed by a signal (e.g. the OOM killer) or its output
+// matches a known transient pattern
+func isTransientFailure(err error, output []byte) bool {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == -1 {
+		return true
+	}
+
+	lowered := strings.ToLower(string(output))
+	for _, pattern := range transientFailurePatterns {
+		if strings.Contains(lowered, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// runFaceDetection runs the face detection script once
+func runFaceDetection(videoPath string, videoID string, segment videoSegment) (*VideoUploadResponse, error) {
 	// Get the absolute path to the Python script
 	pythonScriptPath := filepath.Join("python", "face_detect.py")
 
@@ -265,13 +720,59 @@ func processVideoWithPython(videoPath string, videoID string) (*VideoUploadRespo
 	}
 
 	// Execute Python script with virtual environment and video ID
-	cmd := exec.Command("venv/bin/python3", pythonScriptPath, videoPath, "--video-id", videoID)
+	args := []string{pythonScriptPath, videoPath, "--video-id", videoID, "--model-version", modelVersion}
+	if maxVideoDuration > 0 {
+		args = append(args, "--max-duration", strconv.FormatFloat(maxVideoDuration.Seconds(), 'f', -1, 64))
+	}
+	if faceCropPadding > 0 {
+		args = append(args, "--padding", strconv.FormatFloat(faceCropPadding, 'f', -1, 64))
+	}
+	if minFaceArea > 0 {
+		args = append(args, "--min-face-area", strconv.Itoa(minFaceArea))
+	}
+	if segment.Start > 0 {
+		args = append(args, "--start-seconds", strconv.FormatFloat(segment.Start, 'f', -1, 64))
+	}
+	if segment.End > 0 {
+		args = append(args, "--end-seconds", strconv.FormatFloat(segment.End, 'f', -1, 64))
+	}
+	cmd := exec.Command(pythonInterpreter, args...)
 	cmd.Dir = "." // Set working directory to api root
 
-	output, err := cmd.CombinedOutput()
+	// Unbuffered output reaches the log streams as it is printed
+	cmd.Env = append(os.Environ(), "PYTHONUNBUFFERED=1")
+
+	startedAt := time.Now()
+	processingLogs.publish(videoID, models.ProcessingLogRunHeader(startedAt))
+	writer := &logLineWriter{videoID: videoID}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	err := cmd.Run()
+	writer.flush()
+	output := writer.output.Bytes()
+	if logErr := processingLogs.persist(videoID, startedAt, output); logErr != nil {
+		log.Printf("Warning: Could not save processing log for %s: %v", videoID, logErr)
+	}
 	if err != nil {
 		log.Printf("Python script error: %v", err)
 		log.Printf("Python output: %s", string(output))
+
+		// A rejection is reported as JSON alongside the non-zero exit status
+		if jsonStr, jsonErr := extractLastJSONObject(string(output)); jsonErr == nil {
+			var rejection struct {
+				Rejected        bool    `json:"rejected"`
+				Error           string  `json:"error"`
+				Kind            string  `json:"reason"`
+				DurationSeconds float64 `json:"duration_seconds"`
+			}
+			if json.Unmarshal([]byte(jsonStr), &rejection) == nil && rejection.Rejected {
+				return nil, &videoRejectedError{Reason: rejection.Error, Kind: rejection.Kind, DurationSeconds: rejection.DurationSeconds}
+			}
+		}
+		if isTransientFailure(err, output) {
+			return nil, fmt.Errorf("Python script execution failed: %v (%w)", err, errTransientFailure)
+		}
 		return nil, fmt.Errorf("Python script execution failed: %v", err)
 	}
 
@@ -279,29 +780,117 @@ func processVideoWithPython(videoPath string, videoID string) (*VideoUploadRespo
 	var response VideoUploadResponse
 
 	// Clean the output by finding the last JSON object
-	outputStr := string(output)
-	lastBraceIndex := strings.LastIndex(outputStr, "}")
-	if lastBraceIndex != -1 {
-		// Find the start of the JSON object
-		startIndex := strings.LastIndex(outputStr[:lastBraceIndex+1], "{")
-		if startIndex != -1 {
-			jsonStr := outputStr[startIndex : lastBraceIndex+1]
-			if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
-				log.Printf("Failed to parse Python output: %s", jsonStr)
-				return nil, fmt.Errorf("failed to parse Python script output: %v", err)
-			}
-		} else {
-			return nil, fmt.Errorf("no valid JSON found in Python output")
+	jsonStr, err := extractLastJSONObject(string(output))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
+		log.Printf("Failed to parse Python output: %s", jsonStr)
+		return nil, fmt.Errorf("failed to parse Python script output: %v", err)
+	}
+
+	return &response, nil
+}
+
+// videoRejectedError reports that the Python script refused to process a
+// video, e.g. because it exceeds the maximum duration
+type videoRejectedError struct {
+	Reason          string
+	Kind            string // "too_long" or "segment_out_of_range"
+	DurationSeconds float64
+}
+
+func (e *videoRejectedError) Error() string {
+	return "video rejected: " + e.Reason
+}
+
+// errorCode returns the API error code for the rejection
+func (e *videoRejectedError) errorCode() string {
+	if e.Kind == "segment_out_of_range" {
+		return ErrCodeSegmentOutOfRange
+	}
+	return ErrCodeVideoTooLong
+}
+
+// videoSegment is the part of a video to analyze, in seconds from its start;
+// an End of 0 means the end of the video
+type videoSegment struct {
+	Start float64
+	End   float64
+}
+
+// parseVideoSegment reads the optional start_seconds and end_seconds form fields
+func parseVideoSegment(c *gin.Context) (videoSegment, error) {
+	var segment videoSegment
+	var err error
+	if value := c.PostForm("start_seconds"); value != "" {
+		if segment.Start, err = strconv.ParseFloat(value, 64); err != nil || segment.Start < 0 {
+			return videoSegment{}, fmt.Errorf("start_seconds must be a non-negative number")
+		}
+	}
+	if value := c.PostForm("end_seconds"); value != "" {
+		if segment.End, err = strconv.ParseFloat(value, 64); err != nil || segment.End <= segment.Start {
+			return videoSegment{}, fmt.Errorf("end_seconds must be a number greater than start_seconds")
 		}
+	}
+	return segment, nil
+}
+
+// extractLastJSONObject returns the last top-level JSON object printed by a
+// Python script. The scripts print their result with indentation, so the object
+// starts on a line consisting of a single opening brace; nested objects are
+// indented and are not mistaken for the start of the result.
+func extractLastJSONObject(output string) (string, error) {
+	lastBraceIndex := strings.LastIndex(output, "}")
+	if lastBraceIndex == -1 {
+		return "", fmt.Errorf("no JSON object found in Python output")
+	}
+
+	startIndex := strings.LastIndex(output[:lastBraceIndex+1], "\n{")
+	if startIndex != -1 {
+		startIndex++ // Skip the newline
+	} else if strings.HasPrefix(output, "{") {
+		startIndex = 0
 	} else {
-		return nil, fmt.Errorf("no JSON object found in Python output")
+		return "", fmt.Errorf("no valid JSON found in Python output")
 	}
 
-	return &response, nil
+	return output[startIndex : lastBraceIndex+1], nil
+}
+
+// matchedFace is a stored face image that matched the search image
+type matchedFace struct {
+	Image      string  `json:"face"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Ways the face search script can compare face encodings
+const (
+	distanceMetricEuclidean = "euclidean"
+	distanceMetricCosine    = "cosine"
+)
+
+// validDistanceMetrics are the distance metrics face comparisons accept
+var validDistanceMetrics = map[string]bool{
+	distanceMetricEuclidean: true,
+	distanceMetricCosine:    true,
+}
+
+// parseDistanceMetric validates a requested distance metric, defaulting to
+// the configured one when none was requested
+func parseDistanceMetric(value string) (string, error) {
+	if value == "" {
+		return distanceMetric, nil
+	}
+	if !validDistanceMetrics[value] {
+		return "", fmt.Errorf("distance_metric must be %q or %q", distanceMetricEuclidean, distanceMetricCosine)
+	}
+	return value, nil
 }
 
 // compareFacesWithSearchImage compares a search image with stored face images
-func compareFacesWithSearchImage(searchImagePath string, faceImages []string) ([]string, error) {
+// using the given distance metric
+func compareFacesWithSearchImage(ctx context.Context, searchImagePath string, faceImages []string, metric string) ([]matchedFace, error) {
 	// Get the absolute path to the Python script
 	pythonScriptPath := filepath.Join("python", "face_search.py")
 
@@ -314,7 +903,7 @@ func compareFacesWithSearchImage(searchImagePath string, faceImages []string) ([
 	faceImagesStr := strings.Join(faceImages, ",")
 
 	// Execute Python script for face comparison
-	cmd := exec.Command("venv/bin/python3", pythonScriptPath, searchImagePath, "--face-images", faceImagesStr)
+	cmd := exec.CommandContext(ctx, pythonInterpreter, pythonScriptPath, searchImagePath, "--face-images", faceImagesStr, "--model-version", modelVersion, "--distance-metric", metric)
 	cmd.Dir = "." // Set working directory to api root
 
 	output, err := cmd.CombinedOutput()
@@ -326,20 +915,15 @@ func compareFacesWithSearchImage(searchImagePath string, faceImages []string) ([
 
 	// Parse JSON response
 	var result struct {
-		MatchedFaces []string `json:"matched_faces"`
-		Error        string   `json:"error,omitempty"`
-	}
-
-	outputStr := string(output)
-	lastBraceIndex := strings.LastIndex(outputStr, "}")
-	if lastBraceIndex != -1 {
-		startIndex := strings.LastIndex(outputStr[:lastBraceIndex+1], "{")
-		if startIndex != -1 {
-			jsonStr := outputStr[startIndex : lastBraceIndex+1]
-			if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
-				log.Printf("Failed to parse face search output: %s", jsonStr)
-				return nil, fmt.Errorf("failed to parse face search output: %v", err)
-			}
+		MatchedFaces []string      `json:"matched_faces"`
+		MatchScores  []matchedFace `json:"match_scores"`
+		Error        string        `json:"error,omitempty"`
+	}
+
+	if jsonStr, err := extractLastJSONObject(string(output)); err == nil {
+		if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
+			log.Printf("Failed to parse face search output: %s", jsonStr)
+			return nil, fmt.Errorf("failed to parse face search output: %v", err)
 		}
 	}
 
@@ -347,28 +931,126 @@ func compareFacesWithSearchImage(searchImagePath string, faceImages []string) ([
 		return nil, fmt.Errorf("face search error: %s", result.Error)
 	}
 
-	return result.MatchedFaces, nil
+	return result.MatchScores, nil
 }
 
-// isValidVideoFile checks if the uploaded file is a valid video format
-func isValidVideoFile(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	validExtensions := []string{".mp4", ".avi", ".mov", ".mkv", ".wmv", ".flv", ".webm"}
+// Accepted upload extensions, replaced from the configuration at startup
+var (
+	allowedVideoTypes = []string{".mp4", ".avi", ".mov", ".mkv", ".wmv", ".flv", ".webm"}
+	allowedImageTypes = []string{".jpg", ".jpeg", ".png", ".bmp", ".gif"}
+)
 
-	for _, validExt := range validExtensions {
-		if ext == validExt {
-			return true
-		}
+// Face detection settings, set from the configuration
+var (
+	processingMaxRetries   = 2
+	processingRetryBackoff = 2 * time.Second
+	faceCropPadding        = 0.0 // Fraction of the face size added on each side of saved crops
+	minFaceArea            = 0   // Smaller faces, in pixels of bounding box area, are ignored
+	modelVersion           = ""  // Face model version recorded with detection and search results
+	distanceMetric         = distanceMetricEuclidean
+	searchConcurrency      = 4 // Videos compared with a search image at the same time
+)
+
+// ConfigureProcessing sets the retry policy, face crop settings, model version,
+// distance metric and search concurrency for the face scripts
+func ConfigureProcessing(cfg *config.Config) {
+	processingMaxRetries = cfg.ProcessingMaxRetries
+	processingRetryBackoff = cfg.ProcessingRetryBackoff
+	faceCropPadding = cfg.FaceCropPadding
+	minFaceArea = cfg.MinFaceArea
+	modelVersion = cfg.ModelVersion
+	searchConcurrency = max(cfg.SearchConcurrency, 1)
+
+	if validDistanceMetrics[cfg.DistanceMetric] {
+		distanceMetric = cfg.DistanceMetric
+	} else {
+		log.Printf("Warning: Unknown face distance metric %q, using %s", cfg.DistanceMetric, distanceMetricEuclidean)
 	}
-	return false
+}
+
+// maxVideoDuration is the longest video accepted for processing (0 disables the limit)
+var maxVideoDuration time.Duration
+
+// maxClipDuration is the longest clip that can be cut from a video (0 disables the limit)
+var maxClipDuration time.Duration
+
+// partitionVideosByDate stores uploads in videos/YYYY/MM/DD subdirectories
+var partitionVideosByDate bool
+
+// ConfigureUploads sets the accepted video and image extensions, the maximum
+// video duration and the layout of the videos directory
+func ConfigureUploads(cfg *config.Config) {
+	allowedVideoTypes = cfg.AllowedVideoTypes
+	allowedImageTypes = cfg.AllowedImageTypes
+	maxVideoDuration = cfg.MaxVideoDuration
+	maxClipDuration = cfg.MaxClipDuration
+	partitionVideosByDate = cfg.PartitionVideosByDate
+}
+
+// videoDirFor returns the directory an upload at the given time is stored in
+func videoDirFor(uploadTime time.Time) string {
+	if partitionVideosByDate {
+		return filepath.Join(videosDir, uploadTime.Format("2006"), uploadTime.Format("01"), uploadTime.Format("02"))
+	}
+	return videosDir
+}
+
+// isValidVideoFile checks if the uploaded file is a valid video format
+func isValidVideoFile(filename string) bool {
+	return hasAllowedExtension(filename, allowedVideoTypes)
 }
 
 // isValidImageFile checks if the uploaded file is a valid image format
 func isValidImageFile(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	validExtensions := []string{".jpg", ".jpeg", ".png", ".bmp", ".gif"}
+	return hasAllowedExtension(filename, allowedImageTypes)
+}
+
+// imageContentTypes maps image extensions to the content type their files
+// are sniffed as. Files with other allowed extensions are not sniffed.
+var imageContentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+	".webp": "image/webp",
+}
+
+// validateImageUpload checks that an uploaded image has an allowed extension
+// and that its content really is an image of that format, so renamed files
+// of other types are turned away before they are saved
+func validateImageUpload(file *multipart.FileHeader) error {
+	if !isValidImageFile(file.Filename) {
+		return fmt.Errorf("Invalid image file format. Supported formats: %s", formatExtensions(allowedImageTypes))
+	}
+
+	expected, known := imageContentTypes[strings.ToLower(filepath.Ext(file.Filename))]
+	if !known {
+		return nil
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("Failed to read image file")
+	}
+	defer src.Close()
+
+	// DetectContentType looks at no more than the first 512 bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fmt.Errorf("Failed to read image file")
+	}
+	if detected := http.DetectContentType(head[:n]); detected != expected {
+		return fmt.Errorf("File content does not match its %s extension (detected %s)", strings.TrimPrefix(filepath.Ext(file.Filename), "."), detected)
+	}
+	return nil
+}
 
-	for _, validExt := range validExtensions {
+// hasAllowedExtension reports whether the filename ends with one of the extensions
+func hasAllowedExtension(filename string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, validExt := range extensions {
 		if ext == validExt {
 			return true
 		}
@@ -376,6 +1058,15 @@ func isValidImageFile(filename string) bool {
 	return false
 }
 
+// formatExtensions lists extensions for error messages, e.g. "mp4, avi, mov"
+func formatExtensions(extensions []string) string {
+	names := make([]string, len(extensions))
+	for i, ext := range extensions {
+		names[i] = strings.TrimPrefix(ext, ".")
+	}
+	return strings.Join(names, ", ")
+}
+
 // generateImageHash generates an MD5 hash of an image file
 func generateImageHash(filePath string) string {
 	file, err := os.Open(filePath)
@@ -391,3 +1082,19 @@ func generateImageHash(filePath string) string {
 
 	return fmt.Sprintf("%x", hash.Sum(nil))
 }
+
+// fileChecksum returns the hex-encoded SHA-256 hash of a file
+func fileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
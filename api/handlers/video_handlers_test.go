@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useTestWorkDir runs the test in an empty api directory, so uploads are
+// stored in a temporary ../storage and the Python script is not found
+func useTestWorkDir(t *testing.T) string {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "api")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+	return dir
+}
+
+func TestUploadVideoDuplicates(t *testing.T) {
+	const content = "not really a video"
+
+	// Checksum of content, to seed a record with the same content
+	checksumFile := filepath.Join(t.TempDir(), "content.mp4")
+	if err := os.WriteFile(checksumFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := fileChecksum(checksumFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		existing     *models.VideoRecord
+		filename     string
+		force        bool
+		wantConflict bool
+	}{
+		{
+			name:         "same filename",
+			existing:     &models.VideoRecord{ID: "video_1", OriginalFilename: "clip.mp4", Status: "completed"},
+			filename:     "clip.mp4",
+			wantConflict: true,
+		},
+		{
+			name:         "same filename archived",
+			existing:     &models.VideoRecord{ID: "video_1", OriginalFilename: "clip.mp4", Status: "completed", IsArchived: true},
+			filename:     "clip.mp4",
+			wantConflict: true,
+		},
+		{
+			name:         "same content",
+			existing:     &models.VideoRecord{ID: "video_1", OriginalFilename: "other.mp4", Checksum: checksum, Status: "completed"},
+			filename:     "clip.mp4",
+			wantConflict: true,
+		},
+		{
+			name:     "same filename forced",
+			existing: &models.VideoRecord{ID: "video_1", OriginalFilename: "clip.mp4", Status: "completed"},
+			filename: "clip.mp4",
+			force:    true,
+		},
+		{
+			name:     "same content forced",
+			existing: &models.VideoRecord{ID: "video_1", OriginalFilename: "other.mp4", Checksum: checksum, Status: "completed"},
+			filename: "clip.mp4",
+			force:    true,
+		},
+		{
+			name:     "retry of failed upload",
+			existing: &models.VideoRecord{ID: "video_1", OriginalFilename: "clip.mp4", Checksum: checksum, Status: "failed"},
+			filename: "clip.mp4",
+		},
+		{
+			name:     "retry of rejected upload",
+			existing: &models.VideoRecord{ID: "video_1", OriginalFilename: "clip.mp4", Checksum: checksum, Status: "rejected"},
+			filename: "clip.mp4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestWorkDir(t)
+			storage := useTestStorage(t)
+			if err := storage.AddRecord(tt.existing); err != nil {
+				t.Fatal(err)
+			}
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, err := form.CreateFormFile("video", tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte(content))
+			if tt.force {
+				form.WriteField("force", "true")
+			}
+			form.Close()
+
+			r := gin.New()
+			r.POST("/upload", UploadVideoHandler)
+			req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if tt.wantConflict {
+				if w.Code != http.StatusConflict {
+					t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+				}
+				var response struct {
+					Code       string `json:"code"`
+					ExistingID string `json:"existing_id"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatal(err)
+				}
+				if response.Code != ErrCodeDuplicateVideo || response.ExistingID != tt.existing.ID {
+					t.Errorf("response = %+v, want %s pointing at %s", response, ErrCodeDuplicateVideo, tt.existing.ID)
+				}
+				if len(storage.Records) != 1 {
+					t.Errorf("%d records stored, want only the existing one", len(storage.Records))
+				}
+				return
+			}
+
+			// The upload is accepted; processing then fails as there is no Python script here
+			if w.Code == http.StatusConflict {
+				t.Fatalf("status = %d, want the upload accepted: %s", w.Code, w.Body.String())
+			}
+			if len(storage.Records) != 2 {
+				t.Errorf("%d records stored, want the existing one and the new upload", len(storage.Records))
+			}
+		})
+	}
+}
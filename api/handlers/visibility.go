@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"video-processing-backend/middleware"
+	"video-processing-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getVisibleRecord returns a video record the caller may see. Restricted
+// videos are reported as missing to callers without the restricted scope so
+// their existence is not revealed.
+func getVisibleRecord(c *gin.Context, id string) (*models.VideoRecord, bool) {
+	record, exists := videoStorage.GetRecord(id)
+	if !exists || (record.IsRestricted() && !middleware.HasRestrictedAccess(c)) {
+		return nil, false
+	}
+	return record, true
+}
+
+// visibleRecords filters out the restricted videos the caller may not see
+func visibleRecords(c *gin.Context, records []*models.VideoRecord) []*models.VideoRecord {
+	if middleware.HasRestrictedAccess(c) {
+		return records
+	}
+
+	visible := []*models.VideoRecord{}
+	for _, record := range records {
+		if !record.IsRestricted() {
+			visible = append(visible, record)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds of the timeout of WaitForVideoHandler
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 120 * time.Second
+)
+
+// WaitForVideoHandler long-polls until a video being processed reaches a
+// final status or the timeout elapses, then returns the video. Videos that
+// are not processing are returned right away. timed_out is true when the
+// video was still processing when the timeout elapsed.
+func WaitForVideoHandler(c *gin.Context) {
+	timeout := defaultWaitTimeout
+	if raw := c.Query("timeout"); raw != "" {
+		seconds, err := strconv.ParseFloat(raw, 64)
+		if err != nil || seconds <= 0 || seconds > maxWaitTimeout.Seconds() {
+			respondError(c, http.StatusBadRequest, ErrCodeInvalidParameter, "timeout must be a positive number of seconds, at most "+strconv.Itoa(int(maxWaitTimeout.Seconds())))
+			return
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+
+	// Subscribe before checking the status so a completion in between is not missed
+	events := activity.subscribe()
+	defer activity.unsubscribe(events)
+
+	id := videoIDParam(c)
+	record, exists := getVisibleRecord(c, id)
+	if !exists {
+		respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+		return
+	}
+
+	if record.Status == "processing" {
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+
+	wait:
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case <-deadline.C:
+				break wait
+			case event := <-events:
+				if event.VideoID == record.ID && (event.Type == ActivityAnalysisCompleted || event.Type == ActivityAnalysisFailed) {
+					break wait
+				}
+			}
+		}
+
+		// The video may have been deleted while waiting
+		found, _ := videoStorage.LookupRecords([]string{record.ID})
+		if len(found) == 0 {
+			respondError(c, http.StatusNotFound, ErrCodeVideoNotFound, "Video record not found")
+			return
+		}
+		record = found[0]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"video":     record,
+		"timed_out": record.Status == "processing",
+	})
+}
@@ -3,85 +3,171 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
+	"video-processing-backend/config"
 	"video-processing-backend/handlers"
+	"video-processing-backend/middleware"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// Load configuration from the environment
+	cfg := config.Load()
+
 	// Set Gin to release mode for production
 	gin.SetMode(gin.ReleaseMode)
 
-	// Create Gin router
-	r := gin.Default()
+	// Create Gin router with request IDs and panic recovery
+	r := gin.New()
+
+	// Only take client IPs from X-Forwarded-For when it was set by a known
+	// proxy; otherwise any client could pick its own IP
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(middleware.Logger(), middleware.RequestID(), middleware.AccessScope(cfg.RestrictedAccessKey))
+	if cfg.GzipEnabled {
+		// Registered before recovery so error responses from panics are compressed too
+		r.Use(middleware.Gzip(cfg.GzipMinSize))
+	}
+	r.Use(middleware.Recovery())
 
 	// Configure CORS for API usage
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"}
-	config.ExposeHeaders = []string{"Content-Length", "Content-Type"}
-	r.Use(cors.New(config))
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowAllOrigins = true
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "X-Request-ID", middleware.AccessKeyHeader}
+	corsConfig.ExposeHeaders = []string{"Content-Length", "Content-Type", "X-Request-ID"}
+	corsConfig.MaxAge = 12 * time.Hour // Let browsers cache preflight responses
+	r.Use(cors.New(corsConfig))
 
 	// Create upload directories if they don't exist
 	os.MkdirAll("../storage/videos", 0755)
 	os.MkdirAll("../storage/faces", 0755)
 	os.MkdirAll("../storage/data", 0755)
 	os.MkdirAll("../storage/temp", 0755)
+	os.MkdirAll("../storage/searches", 0755)
+
+	// Apply upload and processing settings from the configuration
+	handlers.ConfigureUploads(cfg)
+	handlers.ConfigureProcessing(cfg)
+	handlers.ConfigureLinks(cfg)
 
 	// Initialize video storage
-	handlers.InitializeStorage()
+	handlers.InitializeStorage(cfg)
+
+	// Load the processing dependencies while already serving; /readyz
+	// returns 503 until this is done
+	go handlers.WarmUp()
+
+	// Start background retention of old videos
+	handlers.StartRetentionJanitor(cfg)
 
 	// Setup API routes
-	setupAPIRoutes(r)
+	setupAPIRoutes(r, cfg)
 
-	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	// Answer unknown paths with 404 and known paths with the wrong method with 405
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(handlers.NotFoundHandler)
+	r.NoMethod(handlers.MethodNotAllowedHandler(r))
 
-	log.Printf("Backend API server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
+	// Start server
+	log.Printf("Backend API server starting on port %s", cfg.Port)
+	if err := r.Run(":" + cfg.Port); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
 
-func setupAPIRoutes(r *gin.Engine) {
+func setupAPIRoutes(r *gin.Engine, cfg *config.Config) {
+	// Stop a single client from flooding the server with uploads
+	uploadHandlers := []gin.HandlerFunc{handlers.UploadVideoHandler}
+	if cfg.MaxUploadsPerClient > 0 {
+		uploadHandlers = append([]gin.HandlerFunc{middleware.ConcurrentPerClient(cfg.MaxUploadsPerClient)}, uploadHandlers...)
+	}
+
+	// Bound face comparisons so a stuck one returns 408 instead of hanging
+	withSearchTimeout := func(handler gin.HandlerFunc) []gin.HandlerFunc {
+		if cfg.SearchTimeout > 0 {
+			return []gin.HandlerFunc{middleware.Timeout(cfg.SearchTimeout), handler}
+		}
+		return []gin.HandlerFunc{handler}
+	}
+
 	// API routes
 	api := r.Group("/api")
 	{
 		// Health check
 		api.GET("/health", handlers.HealthCheckHandler)
+		api.GET("/livez", handlers.LivenessHandler)
+		api.GET("/readyz", handlers.ReadinessHandler)
 
 		// Video upload and processing
-		api.POST("/upload-video", handlers.UploadVideoHandler)
-		api.POST("/search-by-face", handlers.SearchByFaceHandler)
+		api.POST("/upload-video", uploadHandlers...)
+		api.POST("/search-by-face", withSearchTimeout(handlers.SearchByFaceHandler)...)
 
 		// Storage management routes
 		api.GET("/videos", handlers.ListVideosHandler)
 		api.GET("/videos/active", handlers.ListActiveVideosHandler)
 		api.GET("/videos/archived", handlers.ListArchivedVideosHandler)
 		api.GET("/videos/search", handlers.SearchVideosHandler)
+		api.GET("/videos/unanalyzed", handlers.ListUnanalyzedVideosHandler)
+		api.POST("/videos/batch-get", handlers.BatchGetVideosHandler)
 		api.GET("/videos/:id", handlers.GetVideoHandler)
+		api.PATCH("/videos/:id", handlers.PatchVideoHandler)
 		api.DELETE("/videos/:id", handlers.DeleteVideoHandler)
-		api.POST("/videos/:id/restore", handlers.RestoreVideoHandler)
+		api.POST("/videos/:id/archive", handlers.ArchiveVideoHandler)
+		api.POST("/videos/:id/unarchive", handlers.RestoreVideoHandler)
+		api.POST("/videos/:id/restore", handlers.RestoreVideoHandler) // Legacy alias of /unarchive
+		api.PUT("/videos/:id/location", handlers.SetVideoLocationHandler)
+		api.GET("/videos/:id/locations", handlers.GetVideoLocationsHandler)
+		api.PUT("/videos/:id/locations", handlers.SetVideoLocationsHandler)
+		api.GET("/videos/:id/geojson", handlers.GetVideoGeoJSONHandler)
+		api.GET("/videos/:id/download-faces.json", handlers.DownloadFacesHandler)
+		api.GET("/videos/:id/logs", handlers.GetVideoLogsHandler)
+		api.GET("/videos/:id/logs/stream", handlers.StreamVideoLogsHandler)
+		api.GET("/videos/:id/failure-details", handlers.GetVideoFailureDetailsHandler)
+		api.GET("/videos/:id/wait", handlers.WaitForVideoHandler)
+		api.POST("/videos/:id/review", handlers.ReviewVideoHandler)
+		api.POST("/videos/:id/dedup-faces", handlers.DedupVideoFacesHandler)
+		api.GET("/videos/:id/similar", withSearchTimeout(handlers.SimilarVideosHandler)...)
 		api.GET("/videos/stats", handlers.GetVideoStatsHandler)
 		api.POST("/videos/cleanup", handlers.CleanupOldVideosHandler)
 		api.POST("/videos/reset-database", handlers.ResetDatabaseHandler)
+		api.POST("/storage/reindex", handlers.ReindexStorageHandler)
+
+		// Field documentation for analysis results
+		api.GET("/schema/analysis", handlers.GetAnalysisSchemaHandler)
+
+		// Backup and migration bundles
+		api.GET("/export/bundle", handlers.ExportBundleHandler)
+		api.POST("/import/bundle", handlers.ImportBundleHandler)
+
+		// Locations and people across all videos
+		api.GET("/locations", handlers.ListLocationsHandler)
+		api.GET("/stats/faces-by-location", handlers.GetFacesByLocationHandler)
+		api.GET("/persons", handlers.ListPersonsHandler)
 
 		// Search history endpoints
 		api.GET("/search-history", handlers.GetSearchHistoryHandler)
 		api.GET("/search-history/stats", handlers.GetSearchHistoryStatsHandler)
+		api.GET("/search-history/:id/image", handlers.GetSearchImageHandler)
+		api.POST("/search-history/:id/extend", withSearchTimeout(handlers.ExtendSearchHandler)...)
+
+		// Live feed of processing activity
+		api.GET("/activity/stream", handlers.ActivityStreamHandler)
 
 		// Video preview and file serving
 		api.GET("/videos/:id/preview", handlers.GetVideoPreviewHandler)
 		api.GET("/videos/:id/file", handlers.GetVideoFileHandler)
+		api.GET("/videos/:id/verify", handlers.VerifyVideoHandler)
+		api.GET("/videos/:id/overlay", handlers.GetVideoOverlayHandler)
+		api.GET("/videos/:id/clip", handlers.GetVideoClipHandler)
 
 		// Face images serving
-		api.Static("/faces", "../storage/faces")
+		api.GET("/faces/:filename", handlers.ServeFaceHandler)
 	}
 
 	// Root endpoint for API info
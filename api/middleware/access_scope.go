@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessKeyHeader carries the key that grants access to restricted videos
+const AccessKeyHeader = "X-Access-Key"
+
+// restrictedScopeKey is the context key set for callers with the restricted scope
+const restrictedScopeKey = "restricted_access"
+
+// AccessScope grants the restricted scope to requests that send the
+// configured key in X-Access-Key. With an empty key nobody has the scope.
+func AccessScope(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(AccessKeyHeader)
+		if key != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+			c.Set(restrictedScopeKey, true)
+		}
+		c.Next()
+	}
+}
+
+// HasRestrictedAccess reports whether the request was granted the restricted scope
+func HasRestrictedAccess(c *gin.Context) bool {
+	return c.GetBool(restrictedScopeKey)
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrentPerClient limits how many requests each client IP may have in
+// flight at once; requests beyond the limit are rejected with 429
+func ConcurrentPerClient(limit int) gin.HandlerFunc {
+	var mu sync.Mutex
+	inFlight := make(map[string]int)
+
+	return func(c *gin.Context) {
+		client := c.ClientIP()
+
+		mu.Lock()
+		if inFlight[client] >= limit {
+			mu.Unlock()
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many concurrent requests from this client",
+				"code":  "too_many_requests",
+			})
+			return
+		}
+		inFlight[client]++
+		mu.Unlock()
+
+		// Release the slot even if the handler panics
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			inFlight[client]--
+			if inFlight[client] == 0 {
+				delete(inFlight, client)
+			}
+		}()
+
+		c.Next()
+	}
+}
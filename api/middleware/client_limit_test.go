@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConcurrentPerClient(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		secondAddr     string
+		secondXFF      string
+		wantCode       int
+	}{
+		{"same client", nil, "192.0.2.1:1000", "", http.StatusTooManyRequests},
+		{"other client", nil, "192.0.2.2:1000", "", http.StatusOK},
+		{"forged forwarded header", nil, "192.0.2.1:1000", "203.0.113.9", http.StatusTooManyRequests},
+		{"forwarded by trusted proxy", []string{"192.0.2.1"}, "192.0.2.1:1000", "203.0.113.9", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			started := make(chan struct{})
+
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.trustedProxies); err != nil {
+				t.Fatal(err)
+			}
+			r.POST("/upload", ConcurrentPerClient(1), func(c *gin.Context) {
+				// The first request holds its slot until released
+				if c.Query("hold") == "true" {
+					close(started)
+					<-release
+				}
+				c.Status(http.StatusOK)
+			})
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodPost, "/upload?hold=true", nil)
+				req.RemoteAddr = "192.0.2.1:1000"
+				r.ServeHTTP(httptest.NewRecorder(), req)
+			}()
+			<-started
+
+			req := httptest.NewRequest(http.MethodPost, "/upload", nil)
+			req.RemoteAddr = tt.secondAddr
+			if tt.secondXFF != "" {
+				req.Header.Set("X-Forwarded-For", tt.secondXFF)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			close(release)
+			wg.Wait()
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
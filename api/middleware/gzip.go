@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gzip compresses JSON and text responses for clients that accept gzip.
+// Responses smaller than minSize bytes are sent uncompressed, and other
+// content such as served images and videos is passed through untouched.
+func Gzip(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+			c.Next()
+			return
+		}
+
+		writer := &gzipWriter{ResponseWriter: c.Writer, minSize: minSize}
+		c.Writer = writer
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+
+		c.Next()
+
+		writer.finish()
+		c.Writer = writer.ResponseWriter
+	}
+}
+
+// gzipWriter buffers the start of a response until it is known whether the
+// response is compressible and large enough to be worth compressing
+type gzipWriter struct {
+	gin.ResponseWriter
+	minSize     int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+// Write buffers or compresses the data depending on what has been written so far
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+
+	// Headers are final by the first write, so decide on the content type now
+	if w.Header().Get("Content-Encoding") != "" || !isCompressible(w.Header().Get("Content-Type")) {
+		w.passthrough = true
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minSize {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteString writes a string through Write
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports whether the response has been written to, including data
+// still held in the buffer, so later handlers do not write a second response
+func (w *gzipWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Size returns the number of bytes written so far, including buffered data
+func (w *gzipWriter) Size() int {
+	if w.gz == nil && len(w.buf) > 0 {
+		return len(w.buf)
+	}
+	return w.ResponseWriter.Size()
+}
+
+// Flush flushes compressed data to the client
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// startGzip switches the response to gzip and compresses the buffered data
+func (w *gzipWriter) startGzip() error {
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+
+	buffered := w.buf
+	w.buf = nil
+	_, err := w.gz.Write(buffered)
+	return err
+}
+
+// finish completes the compressed stream or writes out a response that
+// stayed below the size threshold
+func (w *gzipWriter) finish() {
+	if w.gz != nil {
+		w.gz.Close()
+		return
+	}
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+// isCompressible reports whether a content type benefits from compression.
+// Event streams are excluded since buffering would hold back their events.
+func isCompressible(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	if mediaType == "text/event-stream" {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		mediaType == "application/geo+json" ||
+		mediaType == "application/javascript"
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestGzip(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+		wantGzip       bool
+	}{
+		{"large JSON", "gzip", "application/json", large, true},
+		{"small JSON", "gzip", "application/json", "{}", false},
+		{"no gzip support", "", "application/json", large, false},
+		{"image", "gzip", "image/jpeg", large, false},
+		{"event stream", "gzip", "text/event-stream", large, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(Gzip(1024))
+			r.GET("/", func(c *gin.Context) {
+				c.Data(http.StatusOK, tt.contentType, []byte(tt.body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			gotGzip := w.Header().Get("Content-Encoding") == "gzip"
+			if gotGzip != tt.wantGzip {
+				t.Fatalf("gzip = %v, want %v", gotGzip, tt.wantGzip)
+			}
+
+			body := w.Body.String()
+			if gotGzip {
+				reader, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				data, err := io.ReadAll(reader)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				body = string(data)
+			}
+			if body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestGzipWrittenIncludesBufferedData(t *testing.T) {
+	r := gin.New()
+	r.Use(Gzip(1024))
+	r.GET("/", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+		if !c.Writer.Written() {
+			t.Error("Written() = false after a buffered response")
+		}
+		if c.Writer.Size() != len(`{"ok":true}`) {
+			t.Errorf("Size() = %d, want %d", c.Writer.Size(), len(`{"ok":true}`))
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logFieldsKey is the context key holding the fields added with SetLogField
+const logFieldsKey = "log_fields"
+
+// SetLogField attaches a field such as video_id to the request's access log line
+func SetLogField(c *gin.Context, key, value string) {
+	if value == "" {
+		return
+	}
+
+	fields, _ := c.Get(logFieldsKey)
+	fieldMap, ok := fields.(map[string]string)
+	if !ok {
+		fieldMap = make(map[string]string)
+		c.Set(logFieldsKey, fieldMap)
+	}
+	fieldMap[key] = value
+}
+
+// Logger logs each request like gin's default logger, followed by its request
+// ID and any fields handlers attached with SetLogField
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		var context strings.Builder
+		if requestID, ok := param.Keys["request_id"].(string); ok {
+			fmt.Fprintf(&context, " request_id=%s", requestID)
+		}
+		if fields, ok := param.Keys[logFieldsKey].(map[string]string); ok {
+			keys := make([]string, 0, len(fields))
+			for key := range fields {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(&context, " %s=%s", key, fields[key])
+			}
+		}
+
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v%s\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			param.Path,
+			context.String(),
+			param.ErrorMessage,
+		)
+	})
+}
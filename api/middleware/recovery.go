@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery recovers from panics of any type, logs them with request context
+// and a stack trace, and responds with a 500 JSON error
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.Printf("Panic recovered: %s (request_id=%s method=%s path=%s)\n%s",
+					fmt.Sprintf("%v", recovered),
+					c.GetString("request_id"),
+					c.Request.Method,
+					c.Request.URL.Path,
+					debug.Stack())
+
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"error": "Internal server error",
+					"code":  "internal_error",
+				})
+			}
+		}()
+
+		c.Next()
+	}
+}
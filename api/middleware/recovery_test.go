@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     interface{}
+		wantInLog string
+	}{
+		{"error", errors.New("database handle is nil"), "database handle is nil"},
+		{"string", "unexpected state", "unexpected state"},
+		{"struct", struct{ Code int }{42}, "{42}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			r := gin.New()
+			r.Use(RequestID(), Recovery())
+			r.POST("/videos/:id/review", func(c *gin.Context) {
+				panic(tt.value)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/videos/video_1/review", nil)
+			req.Header.Set(RequestIDHeader, "req-1234")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+			}
+			if body := w.Body.String(); body != `{"code":"internal_error","error":"Internal server error"}` {
+				t.Errorf("body = %s", body)
+			}
+
+			logged := logs.String()
+			for _, want := range []string{"Panic recovered: " + tt.wantInLog, "request_id=req-1234", "method=POST", "path=/videos/video_1/review", "goroutine"} {
+				if !strings.Contains(logged, want) {
+					t.Errorf("log does not contain %q:\n%s", want, logged)
+				}
+			}
+		})
+	}
+}
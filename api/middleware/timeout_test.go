@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		gzip     bool
+		handler  gin.HandlerFunc
+		wantCode int
+		wantBody string
+	}{
+		{
+			name: "handler finishes in time",
+			handler: func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"ok": true})
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"ok":true}`,
+		},
+		{
+			name: "handler returns without a response",
+			handler: func(c *gin.Context) {
+				<-c.Request.Context().Done()
+			},
+			wantCode: http.StatusRequestTimeout,
+			wantBody: `{"code":"request_timeout","error":"Request timed out"}`,
+		},
+		{
+			name: "handler writes its own timeout",
+			handler: func(c *gin.Context) {
+				<-c.Request.Context().Done()
+				c.JSON(http.StatusRequestTimeout, gin.H{"code": "request_timeout", "error": "Search timed out"})
+			},
+			wantCode: http.StatusRequestTimeout,
+			wantBody: `{"code":"request_timeout","error":"Search timed out"}`,
+		},
+		{
+			name: "handler writes its own timeout behind gzip",
+			gzip: true,
+			handler: func(c *gin.Context) {
+				<-c.Request.Context().Done()
+				c.JSON(http.StatusRequestTimeout, gin.H{"code": "request_timeout", "error": "Search timed out"})
+			},
+			wantCode: http.StatusRequestTimeout,
+			wantBody: `{"code":"request_timeout","error":"Search timed out"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if tt.gzip {
+				r.Use(Gzip(1024))
+			}
+			r.GET("/", Timeout(10*time.Millisecond), tt.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.wantBody)
+			}
+			if !json.Valid(w.Body.Bytes()) {
+				t.Errorf("body is not a single JSON value: %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// earthRadiusKm is the mean Earth radius used for great-circle distances
+const earthRadiusKm = 6371.0
+
+// HaversineKm returns the great-circle distance in kilometers between two points
+func HaversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// HasCoordinates reports whether the record carries GPS coordinates
+func (r *VideoRecord) HasCoordinates() bool {
+	return r.Latitude != 0 || r.Longitude != 0
+}
+
+// NearestDistanceKm returns the distance from the point to the closest of the
+// record's locations, considering both its main coordinates and its segments
+func (r *VideoRecord) NearestDistanceKm(lat, lon float64) (float64, bool) {
+	nearest := math.MaxFloat64
+	found := false
+
+	if r.HasCoordinates() {
+		nearest = HaversineKm(lat, lon, r.Latitude, r.Longitude)
+		found = true
+	}
+
+	for _, segment := range r.LocationSegments {
+		distance := HaversineKm(lat, lon, segment.Latitude, segment.Longitude)
+		if distance < nearest {
+			nearest = distance
+		}
+		found = true
+	}
+
+	return nearest, found
+}
+
+// GeoJSONFeatureCollection is a GeoJSON FeatureCollection (RFC 7946)
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature is a GeoJSON Feature with a point geometry
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   GeoJSONPoint           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONPoint is a GeoJSON Point; coordinates are [longitude, latitude]
+type GeoJSONPoint struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// newPointFeature builds a point feature at the given coordinates
+func newPointFeature(lat, lon float64, properties map[string]interface{}) GeoJSONFeature {
+	return GeoJSONFeature{
+		Type:       "Feature",
+		Geometry:   GeoJSONPoint{Type: "Point", Coordinates: []float64{lon, lat}},
+		Properties: properties,
+	}
+}
+
+// GeoJSON returns the record's locations as a FeatureCollection: one feature
+// for its main coordinates carrying the detection results, and one per
+// location segment. ok is false when the record has no locations.
+func (r *VideoRecord) GeoJSON() (GeoJSONFeatureCollection, bool) {
+	collection := GeoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []GeoJSONFeature{},
+	}
+
+	faceImages := r.FaceImages
+	if faceImages == nil {
+		faceImages = []string{}
+	}
+
+	if r.HasCoordinates() {
+		collection.Features = append(collection.Features, newPointFeature(r.Latitude, r.Longitude, map[string]interface{}{
+			"kind":               "video",
+			"video_id":           r.ID,
+			"original_filename":  r.OriginalFilename,
+			"location_name":      r.LocationName,
+			"upload_time":        r.UploadTime,
+			"status":             r.Status,
+			"unique_faces_count": r.UniqueFacesCount,
+			"face_images":        faceImages,
+		}))
+	}
+
+	for i, segment := range r.LocationSegments {
+		collection.Features = append(collection.Features, newPointFeature(segment.Latitude, segment.Longitude, map[string]interface{}{
+			"kind":          "segment",
+			"video_id":      r.ID,
+			"segment":       i,
+			"location_name": segment.LocationName,
+			"start_seconds": segment.StartSeconds,
+			"end_seconds":   segment.EndSeconds,
+		}))
+	}
+
+	return collection, len(collection.Features) > 0
+}
+
+// LocationSummary is a distinct location name with the number of videos
+// recorded there, the unique faces found in them and the mean of their
+// coordinates
+type LocationSummary struct {
+	LocationName string   `json:"location_name"`
+	VideoCount   int      `json:"video_count"`
+	FaceCount    int      `json:"face_count"`
+	Latitude     *float64 `json:"latitude,omitempty"`
+	Longitude    *float64 `json:"longitude,omitempty"`
+}
+
+// ListLocations returns the distinct non-blank location names of all records,
+// most used first. Restricted videos are only counted with includeRestricted.
+func (vs *VideoStorage) ListLocations(includeRestricted bool) []LocationSummary {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	type accumulator struct {
+		count, faces   int
+		latSum, lonSum float64
+		withCoords     int
+	}
+
+	byName := make(map[string]*accumulator)
+	for _, record := range vs.Records {
+		name := strings.TrimSpace(record.LocationName)
+		if name == "" || (record.IsRestricted() && !includeRestricted) {
+			continue
+		}
+
+		acc, exists := byName[name]
+		if !exists {
+			acc = &accumulator{}
+			byName[name] = acc
+		}
+		acc.count++
+		acc.faces += record.UniqueFacesCount
+		if record.HasCoordinates() {
+			acc.latSum += record.Latitude
+			acc.lonSum += record.Longitude
+			acc.withCoords++
+		}
+	}
+
+	locations := []LocationSummary{}
+	for name, acc := range byName {
+		summary := LocationSummary{LocationName: name, VideoCount: acc.count, FaceCount: acc.faces}
+		if acc.withCoords > 0 {
+			lat := acc.latSum / float64(acc.withCoords)
+			lon := acc.lonSum / float64(acc.withCoords)
+			summary.Latitude = &lat
+			summary.Longitude = &lon
+		}
+		locations = append(locations, summary)
+	}
+
+	// Most used first, then alphabetically for a stable order
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].VideoCount != locations[j].VideoCount {
+			return locations[i].VideoCount > locations[j].VideoCount
+		}
+		return locations[i].LocationName < locations[j].LocationName
+	})
+
+	return locations
+}
+
+// ListFacesByLocation returns the same summaries as ListLocations, ordered by
+// the number of faces found at each location, most first
+func (vs *VideoStorage) ListFacesByLocation(includeRestricted bool) []LocationSummary {
+	locations := vs.ListLocations(includeRestricted)
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].FaceCount > locations[j].FaceCount
+	})
+	return locations
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// processingLogsDir holds the output of the face detection runs of each video
+const processingLogsDir = "../storage/logs"
+
+// MaxProcessingLogSize caps a video's processing log; older output is dropped first
+const MaxProcessingLogSize = 256 * 1024
+
+// ProcessingLogTruncatedMarker starts a log whose earlier output was dropped
+const ProcessingLogTruncatedMarker = "[earlier output truncated]"
+
+// ProcessingLogPath returns where the processing log of a video is stored
+func ProcessingLogPath(videoID string) string {
+	return filepath.Join(processingLogsDir, filepath.Base(videoID)+".log")
+}
+
+// ProcessingLogRunHeader is the line that starts the output of a processing
+// run started at the given time
+func ProcessingLogRunHeader(startedAt time.Time) string {
+	return fmt.Sprintf("=== Processing run at %s ===", startedAt.Format(time.RFC3339))
+}
+
+// AppendProcessingLog adds the output of one processing run to a video's log,
+// keeping only the most recent MaxProcessingLogSize bytes
+func AppendProcessingLog(videoID string, startedAt time.Time, output []byte) error {
+	if err := os.MkdirAll(processingLogsDir, 0755); err != nil {
+		return err
+	}
+
+	path := ProcessingLogPath(videoID)
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(existing)
+	buf.WriteString(ProcessingLogRunHeader(startedAt) + "\n")
+	buf.Write(output)
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+
+	data := buf.Bytes()
+	if len(data) > MaxProcessingLogSize {
+		// Cut at a line boundary so the log starts with a whole line
+		data = data[len(data)-MaxProcessingLogSize:]
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+		data = append([]byte(ProcessingLogTruncatedMarker+"\n"), data...)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
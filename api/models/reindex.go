@@ -0,0 +1,215 @@
+package models
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReindexReport summarizes how the records were reconciled with the files on disk
+type ReindexReport struct {
+	RecordsChecked    int               `json:"records_checked"`
+	MissingVideoFiles []string          `json:"missing_video_files"` // IDs of records whose video file is gone
+	UpdatedRecords    []ReindexedRecord `json:"updated_records"`
+	OrphanedVideos    []string          `json:"orphaned_videos"` // Video files not referenced by any record, relative to the videos directory
+	OrphanedFaces     []string          `json:"orphaned_faces"`  // Face images not belonging to any record
+}
+
+// ReindexedRecord describes the face corrections made to a record
+type ReindexedRecord struct {
+	ID              string   `json:"id"`
+	OldFacesCount   int      `json:"old_faces_count"`
+	NewFacesCount   int      `json:"new_faces_count"`
+	RemovedFaces    []string `json:"removed_faces,omitempty"`
+	DiscoveredFaces []string `json:"discovered_faces,omitempty"`
+}
+
+// Reindex reconciles the records with the videos and faces directories. Records
+// whose video file is missing and files no record refers to are reported; the
+// face images of completed records are rebuilt from the "<id>_face_*" files on
+// disk and the corrected records are saved.
+func (vs *VideoStorage) Reindex(videosDir, facesDir string) (*ReindexReport, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	videoFiles, err := listFilesRecursive(videosDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list videos directory: %v", err)
+	}
+	faceFiles, err := listFiles(facesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list faces directory: %v", err)
+	}
+
+	report := &ReindexReport{
+		MissingVideoFiles: []string{},
+		UpdatedRecords:    []ReindexedRecord{},
+		OrphanedVideos:    []string{},
+		OrphanedFaces:     []string{},
+	}
+	referencedVideos := make(map[string]bool)
+	claimedFaces := make(map[string]bool)
+
+	for _, record := range vs.Records {
+		report.RecordsChecked++
+
+		// Videos may be in dated subdirectories, so compare paths relative to the videos directory
+		videoName, err := filepath.Rel(videosDir, record.StoredPath)
+		if err != nil {
+			videoName = filepath.Base(record.StoredPath)
+		}
+		videoName = filepath.ToSlash(videoName)
+		referencedVideos[videoName] = true
+		if !videoFiles[videoName] {
+			report.MissingVideoFiles = append(report.MissingVideoFiles, record.ID)
+		}
+
+		// Faces on disk belong to the record that their name is prefixed with
+		prefix := record.ID + "_face_"
+		var actual []string
+		for name := range faceFiles {
+			if strings.HasPrefix(name, prefix) {
+				claimedFaces[name] = true
+				actual = append(actual, name)
+			}
+		}
+		for _, faceImage := range record.FaceImages {
+			claimedFaces[filepath.Base(faceImage)] = true
+		}
+
+		// Only completed records have a final set of faces to compare with
+		if record.Status != "completed" {
+			continue
+		}
+
+		if change, changed := reconcileFaces(record, actual); changed {
+			report.UpdatedRecords = append(report.UpdatedRecords, change)
+		}
+	}
+
+	for name := range videoFiles {
+		if !referencedVideos[name] {
+			report.OrphanedVideos = append(report.OrphanedVideos, name)
+		}
+	}
+	for name := range faceFiles {
+		if !claimedFaces[name] {
+			report.OrphanedFaces = append(report.OrphanedFaces, name)
+		}
+	}
+
+	sort.Strings(report.MissingVideoFiles)
+	sort.Strings(report.OrphanedVideos)
+	sort.Strings(report.OrphanedFaces)
+	sort.Slice(report.UpdatedRecords, func(i, j int) bool {
+		return report.UpdatedRecords[i].ID < report.UpdatedRecords[j].ID
+	})
+
+	if len(report.UpdatedRecords) > 0 {
+		return report, vs.save()
+	}
+	return report, nil
+}
+
+// reconcileFaces replaces a record's face images with the face files found on
+// disk, reporting whether anything changed
+func reconcileFaces(record *VideoRecord, actual []string) (ReindexedRecord, bool) {
+	sort.Strings(actual)
+
+	onDisk := make(map[string]bool, len(actual))
+	for _, name := range actual {
+		onDisk[name] = true
+	}
+	recorded := make(map[string]bool, len(record.FaceImages))
+	for _, faceImage := range record.FaceImages {
+		recorded[filepath.Base(faceImage)] = true
+	}
+
+	change := ReindexedRecord{ID: record.ID, OldFacesCount: record.UniqueFacesCount}
+	for _, faceImage := range record.FaceImages {
+		if !onDisk[filepath.Base(faceImage)] {
+			change.RemovedFaces = append(change.RemovedFaces, faceImage)
+		}
+	}
+	for _, name := range actual {
+		if !recorded[name] {
+			change.DiscoveredFaces = append(change.DiscoveredFaces, "faces/"+name)
+		}
+	}
+
+	if len(change.RemovedFaces) == 0 && len(change.DiscoveredFaces) == 0 && record.UniqueFacesCount == len(actual) {
+		return change, false
+	}
+
+	faceImages := make([]string, len(actual))
+	for i, name := range actual {
+		faceImages[i] = "faces/" + name
+	}
+	record.FaceImages = faceImages
+	record.UniqueFacesCount = len(faceImages)
+
+	// Drop the boxes of faces that no longer exist
+	var faceBoxes []FaceBox
+	for _, box := range record.FaceBoxes {
+		if onDisk[filepath.Base(box.Face)] {
+			faceBoxes = append(faceBoxes, box)
+		}
+	}
+	record.FaceBoxes = faceBoxes
+
+	change.NewFacesCount = record.UniqueFacesCount
+	return change, true
+}
+
+// listFilesRecursive returns the regular, non-hidden files in a directory and
+// its subdirectories as slash-separated paths relative to it; a missing
+// directory has no files
+func listFilesRecursive(dir string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// listFiles returns the names of the regular, non-hidden files in a
+// directory; a missing directory has no files
+func listFiles(dir string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		// Skip hidden files such as .gitkeep
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			files[entry.Name()] = true
+		}
+	}
+	return files, nil
+}
@@ -3,14 +3,18 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
 // SearchRecord represents a search history record
 type SearchRecord struct {
 	ID              string    `json:"id"`
+	CaseID          string    `json:"case_id,omitempty"`  // Investigation the search belongs to
+	VideoID         string    `json:"video_id,omitempty"` // The only video searched, when the search was limited to one
 	SearchImagePath string    `json:"search_image_path"`
 	SearchTime      time.Time `json:"search_time"`
 	QueryHash       string    `json:"query_hash"` // Hash of the search image for deduplication
@@ -18,11 +22,27 @@ type SearchRecord struct {
 	TotalVideos     int       `json:"total_videos"`
 	MatchedVideos   []string  `json:"matched_videos"` // List of video IDs that had matches
 	ProcessingTime  float64   `json:"processing_time"`
+	ModelVersion    string    `json:"model_version,omitempty"`   // Face model used for the comparison
+	DistanceMetric  string    `json:"distance_metric,omitempty"` // How face encodings were compared
+
+	// Set when the search was last extended to videos added after it ran
+	ExtendedAt *time.Time `json:"extended_at,omitempty"`
+}
+
+// SearchedUntil returns when the search last covered all videos, so videos
+// analyzed later still need to be searched
+func (r *SearchRecord) SearchedUntil() time.Time {
+	if r.ExtendedAt != nil {
+		return *r.ExtendedAt
+	}
+	return r.SearchTime
 }
 
 // SearchHistory manages search history records
 type SearchHistory struct {
+	mu       sync.RWMutex
 	filepath string
+	compact  bool                     // Write the file without indentation
 	Records  map[string]*SearchRecord `json:"records"`
 }
 
@@ -34,8 +54,18 @@ func NewSearchHistory(filepath string) *SearchHistory {
 	}
 }
 
+// SetCompact chooses between compact and indented JSON for the history file
+func (sh *SearchHistory) SetCompact(compact bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.compact = compact
+}
+
 // Load loads search history from JSON file
 func (sh *SearchHistory) Load() error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(sh.filepath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -46,7 +76,7 @@ func (sh *SearchHistory) Load() error {
 	if _, err := os.Stat(sh.filepath); os.IsNotExist(err) {
 		// File doesn't exist, create empty storage
 		sh.Records = make(map[string]*SearchRecord)
-		return sh.Save()
+		return sh.save()
 	}
 
 	// Read existing file
@@ -61,7 +91,16 @@ func (sh *SearchHistory) Load() error {
 	}
 
 	if err := json.Unmarshal(data, &sh); err != nil {
-		return fmt.Errorf("failed to unmarshal history data: %v", err)
+		// Keep the service up with an empty history rather than failing to load
+		backup, backupErr := backUpCorruptFile(sh.filepath)
+		if backupErr != nil {
+			return fmt.Errorf("failed to unmarshal history data: %v (backup failed: %v)", err, backupErr)
+		}
+		sh.Records = make(map[string]*SearchRecord)
+		if err := sh.save(); err != nil {
+			return err
+		}
+		return &CorruptStorageError{Path: sh.filepath, BackupPath: backup, Err: err}
 	}
 
 	return nil
@@ -69,7 +108,14 @@ func (sh *SearchHistory) Load() error {
 
 // Save saves search history to JSON file
 func (sh *SearchHistory) Save() error {
-	data, err := json.MarshalIndent(sh, "", "  ")
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.save()
+}
+
+// save writes the history to disk; the caller must hold the lock
+func (sh *SearchHistory) save() error {
+	data, err := marshalStorageFile(sh, sh.compact)
 	if err != nil {
 		return fmt.Errorf("failed to marshal history data: %v", err)
 	}
@@ -81,21 +127,65 @@ func (sh *SearchHistory) Save() error {
 	return nil
 }
 
+// Snapshot returns the history encoded the same way as the history file
+func (sh *SearchHistory) Snapshot() ([]byte, error) {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return json.MarshalIndent(sh, "", "  ")
+}
+
+// ImportRecords adds the given records, replacing existing records with the
+// same IDs, and returns how many were replaced
+func (sh *SearchHistory) ImportRecords(records map[string]*SearchRecord) (int, error) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	replaced := 0
+	for id, record := range records {
+		if _, exists := sh.Records[id]; exists {
+			replaced++
+		}
+		sh.Records[id] = record
+	}
+	return replaced, sh.save()
+}
+
 // AddRecord adds a new search record
 func (sh *SearchHistory) AddRecord(record *SearchRecord) error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.Records[record.ID] = record
+	return sh.save()
+}
+
+// UpdateRecord replaces an existing search record
+func (sh *SearchHistory) UpdateRecord(record *SearchRecord) error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if _, exists := sh.Records[record.ID]; !exists {
+		return fmt.Errorf("%w: %s", ErrRecordNotFound, record.ID)
+	}
 	sh.Records[record.ID] = record
-	return sh.Save()
+	return sh.save()
 }
 
 // GetRecord retrieves a search record by ID
 func (sh *SearchHistory) GetRecord(id string) (*SearchRecord, bool) {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
 	record, exists := sh.Records[id]
 	return record, exists
 }
 
 // ListRecords returns all search records (sorted by time, newest first)
 func (sh *SearchHistory) ListRecords() []*SearchRecord {
-	var records []*SearchRecord
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	records := []*SearchRecord{}
 	for _, record := range sh.Records {
 		records = append(records, record)
 	}
@@ -114,6 +204,9 @@ func (sh *SearchHistory) ListRecords() []*SearchRecord {
 
 // GetStats returns search history statistics
 func (sh *SearchHistory) GetStats() map[string]interface{} {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
 	totalSearches := len(sh.Records)
 	totalMatches := 0
 	successfulSearches := 0
@@ -125,10 +218,46 @@ func (sh *SearchHistory) GetStats() map[string]interface{} {
 		}
 	}
 
+	// With no searches the rate would be NaN, which cannot be encoded as JSON
+	successRate := 0.0
+	if totalSearches > 0 {
+		successRate = float64(successfulSearches) / float64(totalSearches) * 100
+	}
+
 	return map[string]interface{}{
 		"total_searches":      totalSearches,
 		"successful_searches": successfulSearches,
 		"total_matches_found": totalMatches,
-		"success_rate":        float64(successfulSearches) / float64(totalSearches) * 100,
+		"success_rate":        successRate,
 	}
 }
+
+// CleanupOldRecords removes search records older than the given number of days,
+// along with their stored search images, and returns the number removed
+func (sh *SearchHistory) CleanupOldRecords(daysToKeep int) (int, error) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	cutoffTime := time.Now().AddDate(0, 0, -daysToKeep)
+	removed := 0
+
+	for id, record := range sh.Records {
+		if !record.SearchTime.Before(cutoffTime) {
+			continue
+		}
+
+		if record.SearchImagePath != "" {
+			if err := os.Remove(record.SearchImagePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("Warning: Could not remove search image %s: %v", record.SearchImagePath, err)
+			}
+		}
+		delete(sh.Records, id)
+		removed++
+	}
+
+	if removed > 0 {
+		return removed, sh.save()
+	}
+
+	return 0, nil
+}
@@ -2,36 +2,113 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
+// ErrRecordNotFound is returned when no video record exists with the given ID
+var ErrRecordNotFound = errors.New("record not found")
+
 // VideoRecord represents a video processing record
 type VideoRecord struct {
 	ID               string    `json:"id"`
 	OriginalFilename string    `json:"original_filename"`
 	StoredPath       string    `json:"stored_path"`
+	Checksum         string    `json:"checksum,omitempty"` // SHA-256 of the stored file, recorded at upload
 	UploadTime       time.Time `json:"upload_time"`
-	Status           string    `json:"status"` // "processing", "completed", "failed"
-	ProcessingTime   float64   `json:"processing_time,omitempty"`
+	Status           string    `json:"status"` // "processing", "completed", "failed", "rejected"
+	StatusUpdatedAt  time.Time `json:"status_updated_at,omitempty"`
+	ProcessingTime   float64   `json:"processing_time,omitempty" unit:"seconds"`
 	UniqueFacesCount int       `json:"unique_faces_count,omitempty"`
 	FaceImages       []string  `json:"face_images,omitempty"`
+	FaceBoxes        []FaceBox `json:"face_boxes,omitempty"`
+	Rotation         int       `json:"rotation,omitempty" unit:"degrees clockwise"` // Display rotation from the video metadata
+	DurationSeconds  float64   `json:"duration_seconds,omitempty" unit:"seconds"`
+	ModelVersion     string    `json:"model_version,omitempty"` // Face model that produced the results
 	ErrorMessage     string    `json:"error_message,omitempty"`
 	IsArchived       bool      `json:"is_archived"` // New field to mark as history
 	LastAccessed     time.Time `json:"last_accessed,omitempty"`
 	AccessCount      int       `json:"access_count,omitempty"`
 	// Location information
 	LocationName string  `json:"location_name,omitempty"`
-	Latitude     float64 `json:"latitude,omitempty"`
-	Longitude    float64 `json:"longitude,omitempty"`
+	Latitude     float64 `json:"latitude,omitempty" unit:"degrees"`
+	Longitude    float64 `json:"longitude,omitempty" unit:"degrees"`
+	// Additional locations for footage covering several places
+	LocationSegments []LocationSegment `json:"location_segments,omitempty"`
+	// Operator annotations
+	Tags  []string `json:"tags,omitempty"`
+	Notes string   `json:"notes,omitempty"`
+	// Restricted videos are only visible to callers with the restricted scope
+	Visibility string `json:"visibility,omitempty"` // "public" (or empty) or "restricted"
+	// Part of the video that was analyzed; an end of 0 means the end of the video
+	SegmentStart float64 `json:"segment_start_seconds,omitempty" unit:"seconds"`
+	SegmentEnd   float64 `json:"segment_end_seconds,omitempty" unit:"seconds"`
+	// Whether an operator has checked the footage, and who and when
+	Reviewed   bool       `json:"reviewed"`
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// Video visibility levels
+const (
+	VisibilityPublic     = "public"
+	VisibilityRestricted = "restricted"
+)
+
+// IsRestricted reports whether the video is hidden from callers without the restricted scope
+func (r *VideoRecord) IsRestricted() bool {
+	return r.Visibility == VisibilityRestricted
+}
+
+// FaceBox locates a stored face crop in the sampled frame it was taken from
+type FaceBox struct {
+	Face      string      `json:"face"`
+	Frame     int         `json:"frame"`      // 1-based index of the sampled frame
+	Box       BoundingBox `json:"box"`        // Tight detection box, for overlays
+	PaddedBox BoundingBox `json:"padded_box"` // Area of the saved crop including padding
+	Quality   float64     `json:"quality"`    // 0-1 score from sharpness and brightness
+	// Position of the sampled frame in the video, unset for videos processed
+	// before it was recorded
+	TimestampSeconds *float64 `json:"timestamp_seconds,omitempty" unit:"seconds"`
+}
+
+// Timestamp returns the position of the sampled frame in seconds. Older
+// records only have the frame index, which is converted assuming the default
+// sampling of one frame per second.
+func (b FaceBox) Timestamp() float64 {
+	if b.TimestampSeconds != nil {
+		return *b.TimestampSeconds
+	}
+	return float64(b.Frame - 1)
+}
+
+// BoundingBox is a rectangle in pixel coordinates
+type BoundingBox struct {
+	Top    int `json:"top" unit:"pixels"`
+	Right  int `json:"right" unit:"pixels"`
+	Bottom int `json:"bottom" unit:"pixels"`
+	Left   int `json:"left" unit:"pixels"`
+}
+
+// LocationSegment describes where a time range of a video was recorded
+type LocationSegment struct {
+	StartSeconds float64 `json:"start_seconds" unit:"seconds"`
+	EndSeconds   float64 `json:"end_seconds" unit:"seconds"`
+	LocationName string  `json:"location_name,omitempty"`
+	Latitude     float64 `json:"latitude" unit:"degrees"`
+	Longitude    float64 `json:"longitude" unit:"degrees"`
 }
 
 // VideoStorage manages video records
 type VideoStorage struct {
+	mu       sync.RWMutex
 	filepath string
+	compact  bool                    // Write the file without indentation
 	Records  map[string]*VideoRecord `json:"records"`
 }
 
@@ -43,8 +120,18 @@ func NewVideoStorage(filepath string) *VideoStorage {
 	}
 }
 
+// SetCompact chooses between compact and indented JSON for the storage file
+func (vs *VideoStorage) SetCompact(compact bool) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	vs.compact = compact
+}
+
 // Load loads video records from JSON file
 func (vs *VideoStorage) Load() error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(vs.filepath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -55,7 +142,7 @@ func (vs *VideoStorage) Load() error {
 	if _, err := os.Stat(vs.filepath); os.IsNotExist(err) {
 		// File doesn't exist, create empty storage
 		vs.Records = make(map[string]*VideoRecord)
-		return vs.Save()
+		return vs.save()
 	}
 
 	// Read existing file
@@ -70,7 +157,16 @@ func (vs *VideoStorage) Load() error {
 	}
 
 	if err := json.Unmarshal(data, &vs); err != nil {
-		return fmt.Errorf("failed to unmarshal storage data: %v", err)
+		// Keep the service up with an empty store rather than refusing to start
+		backup, backupErr := backUpCorruptFile(vs.filepath)
+		if backupErr != nil {
+			return fmt.Errorf("failed to unmarshal storage data: %v (backup failed: %v)", err, backupErr)
+		}
+		vs.Records = make(map[string]*VideoRecord)
+		if err := vs.save(); err != nil {
+			return err
+		}
+		return &CorruptStorageError{Path: vs.filepath, BackupPath: backup, Err: err}
 	}
 
 	return nil
@@ -78,7 +174,14 @@ func (vs *VideoStorage) Load() error {
 
 // Save saves video records to JSON file
 func (vs *VideoStorage) Save() error {
-	data, err := json.MarshalIndent(vs, "", "  ")
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	return vs.save()
+}
+
+// save writes the records to disk; the caller must hold the lock
+func (vs *VideoStorage) save() error {
+	data, err := marshalStorageFile(vs, vs.compact)
 	if err != nil {
 		return fmt.Errorf("failed to marshal storage data: %v", err)
 	}
@@ -90,50 +193,123 @@ func (vs *VideoStorage) Save() error {
 	return nil
 }
 
+// Snapshot returns the records encoded the same way as the storage file
+func (vs *VideoStorage) Snapshot() ([]byte, error) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	return json.MarshalIndent(vs, "", "  ")
+}
+
+// ImportRecords adds the given records, replacing existing records with the
+// same IDs, and returns how many were replaced
+func (vs *VideoStorage) ImportRecords(records map[string]*VideoRecord) (int, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	replaced := 0
+	for id, record := range records {
+		if _, exists := vs.Records[id]; exists {
+			replaced++
+		}
+		vs.Records[id] = record
+	}
+	return replaced, vs.save()
+}
+
 // AddRecord adds a new video record
 func (vs *VideoStorage) AddRecord(record *VideoRecord) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
 	vs.Records[record.ID] = record
-	return vs.Save()
+	return vs.save()
 }
 
 // GetRecord retrieves a video record by ID
 func (vs *VideoStorage) GetRecord(id string) (*VideoRecord, bool) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
 	record, exists := vs.Records[id]
 	if exists && record != nil {
 		// Update access statistics
 		record.LastAccessed = time.Now()
 		record.AccessCount++
-		vs.Save() // Save the updated access info
+		vs.save() // Save the updated access info
 	}
 	return record, exists
 }
 
+// LookupRecords returns the records with the given IDs, in the order asked
+// for, and the IDs that have no record. Unlike GetRecord it does not update
+// access statistics, so it never writes to disk.
+func (vs *VideoStorage) LookupRecords(ids []string) ([]*VideoRecord, []string) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	found := []*VideoRecord{}
+	missing := []string{}
+	for _, id := range ids {
+		if record, exists := vs.Records[id]; exists && record != nil {
+			found = append(found, record)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return found, missing
+}
+
 // UpdateRecord updates an existing video record
 func (vs *VideoStorage) UpdateRecord(record *VideoRecord) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
 	if _, exists := vs.Records[record.ID]; !exists {
-		return fmt.Errorf("record not found: %s", record.ID)
+		return fmt.Errorf("%w: %s", ErrRecordNotFound, record.ID)
 	}
 	vs.Records[record.ID] = record
-	return vs.Save()
+	return vs.save()
 }
 
 // DeleteRecord deletes a video record (but keeps the files for history)
 func (vs *VideoStorage) DeleteRecord(id string) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
 	record, exists := vs.Records[id]
 	if !exists {
-		return fmt.Errorf("record not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrRecordNotFound, id)
 	}
 
 	// Mark as archived instead of deleting
 	record.IsArchived = true
 	record.LastAccessed = time.Now()
 	vs.Records[id] = record
-	return vs.Save()
+	return vs.save()
+}
+
+// PurgeRecord permanently deletes a video record along with its video file and face images
+func (vs *VideoStorage) PurgeRecord(id string) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	record, exists := vs.Records[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrRecordNotFound, id)
+	}
+
+	removeRecordFiles(record)
+	delete(vs.Records, id)
+	return vs.save()
 }
 
-// ListRecords returns all video records
+// ListRecords returns all video records. The List methods never return nil,
+// so empty results encode as [] rather than null.
 func (vs *VideoStorage) ListRecords() []*VideoRecord {
-	var records []*VideoRecord
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	records := []*VideoRecord{}
 	for _, record := range vs.Records {
 		records = append(records, record)
 	}
@@ -142,7 +318,10 @@ func (vs *VideoStorage) ListRecords() []*VideoRecord {
 
 // ListActiveRecords returns only non-archived records
 func (vs *VideoStorage) ListActiveRecords() []*VideoRecord {
-	var records []*VideoRecord
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	records := []*VideoRecord{}
 	for _, record := range vs.Records {
 		if !record.IsArchived {
 			records = append(records, record)
@@ -153,7 +332,10 @@ func (vs *VideoStorage) ListActiveRecords() []*VideoRecord {
 
 // ListArchivedRecords returns only archived records (history)
 func (vs *VideoStorage) ListArchivedRecords() []*VideoRecord {
-	var records []*VideoRecord
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	records := []*VideoRecord{}
 	for _, record := range vs.Records {
 		if record.IsArchived {
 			records = append(records, record)
@@ -162,8 +344,56 @@ func (vs *VideoStorage) ListArchivedRecords() []*VideoRecord {
 	return records
 }
 
+// FindDuplicate returns a record, active or archived, with the same original
+// filename or checksum; empty values never match. Failed and rejected videos
+// are skipped so their uploads can be retried.
+func (vs *VideoStorage) FindDuplicate(filename, checksum string) *VideoRecord {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	for _, record := range vs.Records {
+		if record.Status == "failed" || record.Status == "rejected" {
+			continue
+		}
+		if filename != "" && record.OriginalFilename == filename {
+			return record
+		}
+		if checksum != "" && record.Checksum == checksum {
+			return record
+		}
+	}
+	return nil
+}
+
+// ListUnanalyzedRecords returns records without a successful analysis,
+// i.e. those that failed or are still processing
+func (vs *VideoStorage) ListUnanalyzedRecords() []*VideoRecord {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	records := []*VideoRecord{}
+	for _, record := range vs.Records {
+		if record.Status == "failed" || record.Status == "processing" {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// TimeInStatus returns how long the record has been in its current status
+func (r *VideoRecord) TimeInStatus(now time.Time) time.Duration {
+	since := r.StatusUpdatedAt
+	if since.IsZero() {
+		since = r.UploadTime
+	}
+	return now.Sub(since)
+}
+
 // GetStats returns storage statistics
 func (vs *VideoStorage) GetStats() map[string]interface{} {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
 	totalRecords := len(vs.Records)
 	activeRecords := 0
 	archivedRecords := 0
@@ -197,7 +427,11 @@ func (vs *VideoStorage) GetStats() map[string]interface{} {
 }
 
 // CleanupOldRecords removes very old archived records (optional, for disk space management)
-func (vs *VideoStorage) CleanupOldRecords(daysToKeep int) error {
+// and returns the number of records removed
+func (vs *VideoStorage) CleanupOldRecords(daysToKeep int) (int, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
 	cutoffTime := time.Now().AddDate(0, 0, -daysToKeep)
 	var recordsToDelete []string
 
@@ -213,32 +447,125 @@ func (vs *VideoStorage) CleanupOldRecords(daysToKeep int) error {
 	}
 
 	if len(recordsToDelete) > 0 {
-		return vs.Save()
+		return len(recordsToDelete), vs.save()
 	}
 
-	return nil
+	return 0, nil
+}
+
+// ArchiveCompletedBefore archives active completed records uploaded before the
+// cutoff and returns the IDs of the records archived
+func (vs *VideoStorage) ArchiveCompletedBefore(cutoff time.Time) ([]string, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	var archived []string
+	for id, record := range vs.Records {
+		if !record.IsArchived && record.Status == "completed" && record.UploadTime.Before(cutoff) {
+			record.IsArchived = true
+			record.LastAccessed = time.Now()
+			archived = append(archived, id)
+		}
+	}
+
+	if len(archived) > 0 {
+		return archived, vs.save()
+	}
+
+	return nil, nil
+}
+
+// PurgeArchivedBefore permanently deletes archived records last accessed
+// before the cutoff, along with their video file, face images and processing
+// log, and returns the IDs of the records purged
+func (vs *VideoStorage) PurgeArchivedBefore(cutoff time.Time) ([]string, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	var purged []string
+	for id, record := range vs.Records {
+		if record.IsArchived && record.LastAccessed.Before(cutoff) {
+			removeRecordFiles(record)
+			delete(vs.Records, id)
+			purged = append(purged, id)
+		}
+	}
+
+	if len(purged) > 0 {
+		return purged, vs.save()
+	}
+
+	return nil, nil
 }
 
 // ResetDatabase completely resets the database and removes all files
 func (vs *VideoStorage) ResetDatabase() error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
 	// Remove all video files
 	for _, record := range vs.Records {
-		if err := os.Remove(record.StoredPath); err != nil {
-			log.Printf("Warning: Could not remove video file %s: %v", record.StoredPath, err)
-		}
-
-		// Remove face images
-		for _, faceImage := range record.FaceImages {
-			facePath := filepath.Join("../storage/faces", filepath.Base(faceImage))
-			if err := os.Remove(facePath); err != nil {
-				log.Printf("Warning: Could not remove face image %s: %v", facePath, err)
-			}
-		}
+		removeRecordFiles(record)
 	}
 
 	// Clear all records
 	vs.Records = make(map[string]*VideoRecord)
 
 	// Save empty database
-	return vs.Save()
+	return vs.save()
+}
+
+// removeRecordFiles removes the video file and face images of a record
+func removeRecordFiles(record *VideoRecord) {
+	if err := os.Remove(record.StoredPath); err != nil {
+		log.Printf("Warning: Could not remove video file %s: %v", record.StoredPath, err)
+	}
+
+	// Remove face images
+	for _, faceImage := range record.FaceImages {
+		facePath := filepath.Join("../storage/faces", filepath.Base(faceImage))
+		if err := os.Remove(facePath); err != nil {
+			log.Printf("Warning: Could not remove face image %s: %v", facePath, err)
+		}
+	}
+
+	logPath := ProcessingLogPath(record.ID)
+	if err := os.Remove(logPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Could not remove processing log %s: %v", logPath, err)
+	}
+}
+
+// CorruptStorageError is returned by Load when a storage file could not be
+// decoded. The file was moved to BackupPath and the storage started empty.
+type CorruptStorageError struct {
+	Path       string
+	BackupPath string
+	Err        error
+}
+
+func (e *CorruptStorageError) Error() string {
+	return fmt.Sprintf("storage file %s is corrupt and was moved to %s: %v", e.Path, e.BackupPath, e.Err)
+}
+
+func (e *CorruptStorageError) Unwrap() error {
+	return e.Err
+}
+
+// backUpCorruptFile moves a storage file that could not be decoded aside,
+// named with the current time, so it can be inspected or repaired by hand
+func backUpCorruptFile(path string) (string, error) {
+	backup := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102_150405"))
+	if err := os.Rename(path, backup); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
+
+// marshalStorageFile encodes a storage file, indented for readability unless
+// compact output is requested
+func marshalStorageFile(v interface{}, compact bool) ([]byte, error) {
+	if compact {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "  ")
 }
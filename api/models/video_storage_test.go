@@ -0,0 +1,204 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPurgeArchivedBefore(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -30)
+
+	records := []struct {
+		id         string
+		archived   bool
+		accessed   time.Time
+		wantPurged bool
+	}{
+		{"old_archived", true, now.AddDate(0, 0, -40), true},
+		{"recent_archived", true, now.AddDate(0, 0, -10), false},
+		{"old_active", false, now.AddDate(0, 0, -40), false},
+	}
+
+	storage := NewVideoStorage(filepath.Join(dir, "videos.json"))
+	for _, r := range records {
+		videoPath := filepath.Join(dir, r.id+".mp4")
+		if err := os.WriteFile(videoPath, []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		storage.Records[r.id] = &VideoRecord{ID: r.id, StoredPath: videoPath, IsArchived: r.archived, LastAccessed: r.accessed}
+	}
+
+	purged, err := storage.PurgeArchivedBefore(cutoff)
+	if err != nil {
+		t.Fatalf("PurgeArchivedBefore: %v", err)
+	}
+	if len(purged) != 1 || purged[0] != "old_archived" {
+		t.Errorf("purged = %v, want [old_archived]", purged)
+	}
+
+	for _, r := range records {
+		_, exists := storage.Records[r.id]
+		if exists == r.wantPurged {
+			t.Errorf("%s: record kept = %v, want %v", r.id, exists, !r.wantPurged)
+		}
+		_, err := os.Stat(filepath.Join(dir, r.id+".mp4"))
+		if fileKept := err == nil; fileKept == r.wantPurged {
+			t.Errorf("%s: video file kept = %v, want %v", r.id, fileKept, !r.wantPurged)
+		}
+	}
+
+	// The storage file reflects the purge
+	reloaded := NewVideoStorage(filepath.Join(dir, "videos.json"))
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var ids []string
+	for id := range reloaded.Records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if len(ids) != 2 || ids[0] != "old_active" || ids[1] != "recent_archived" {
+		t.Errorf("reloaded records = %v, want [old_active recent_archived]", ids)
+	}
+}
+
+func TestFaceBoxTimestamp(t *testing.T) {
+	recorded := 580.78
+
+	tests := []struct {
+		name string
+		box  FaceBox
+		want float64
+	}{
+		{"recorded timestamp", FaceBox{Frame: 601, TimestampSeconds: &recorded}, 580.78},
+		{"legacy first frame", FaceBox{Frame: 1}, 0},
+		{"legacy frame index", FaceBox{Frame: 601}, 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.box.Timestamp(); got != tt.want {
+				t.Errorf("Timestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCorruptStorage(t *testing.T) {
+	tests := []struct {
+		name string
+		load func(path string) (int, error)
+	}{
+		{"video storage", func(path string) (int, error) {
+			storage := NewVideoStorage(path)
+			err := storage.Load()
+			return len(storage.Records), err
+		}},
+		{"search history", func(path string) (int, error) {
+			history := NewSearchHistory(path)
+			err := history.Load()
+			return len(history.Records), err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.json")
+			corrupt := []byte(`{"records": {"video_1": `)
+			if err := os.WriteFile(path, corrupt, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			count, err := tt.load(path)
+			var corruptErr *CorruptStorageError
+			if !errors.As(err, &corruptErr) {
+				t.Fatalf("Load error = %v, want a CorruptStorageError", err)
+			}
+			if count != 0 {
+				t.Errorf("loaded %d records, want an empty store", count)
+			}
+
+			// The corrupt data is kept aside untouched
+			backup, err := os.ReadFile(corruptErr.BackupPath)
+			if err != nil {
+				t.Fatalf("reading backup: %v", err)
+			}
+			if string(backup) != string(corrupt) {
+				t.Errorf("backup = %q, want %q", backup, corrupt)
+			}
+
+			// A fresh, valid file replaces it so the next start is clean
+			if count, err := tt.load(path); err != nil || count != 0 {
+				t.Errorf("reload = %d records, %v; want an empty store without error", count, err)
+			}
+		})
+	}
+}
+
+func TestBackUpCorruptFileMissing(t *testing.T) {
+	if _, err := backUpCorruptFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("backUpCorruptFile of a missing file succeeded")
+	}
+}
+
+func TestImportRecords(t *testing.T) {
+	tests := []struct {
+		name         string
+		existing     []string
+		imported     []string
+		wantReplaced int
+		wantIDs      []string
+	}{
+		{"into empty storage", nil, []string{"video_1", "video_2"}, 0, []string{"video_1", "video_2"}},
+		{"merge", []string{"video_1"}, []string{"video_2"}, 0, []string{"video_1", "video_2"}},
+		{"replace", []string{"video_1", "video_2"}, []string{"video_2", "video_3"}, 1, []string{"video_1", "video_2", "video_3"}},
+		{"nothing", []string{"video_1"}, nil, 0, []string{"video_1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "videos.json")
+			storage := NewVideoStorage(path)
+			for _, id := range tt.existing {
+				storage.Records[id] = &VideoRecord{ID: id, Status: "processing"}
+			}
+
+			records := make(map[string]*VideoRecord)
+			for _, id := range tt.imported {
+				records[id] = &VideoRecord{ID: id, Status: "completed"}
+			}
+
+			replaced, err := storage.ImportRecords(records)
+			if err != nil {
+				t.Fatalf("ImportRecords: %v", err)
+			}
+			if replaced != tt.wantReplaced {
+				t.Errorf("replaced = %d, want %d", replaced, tt.wantReplaced)
+			}
+
+			// Imported records win, and the result is saved
+			reloaded := NewVideoStorage(path)
+			if err := reloaded.Load(); err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			var ids []string
+			for id, record := range reloaded.Records {
+				ids = append(ids, id)
+				if _, imported := records[id]; imported && record.Status != "completed" {
+					t.Errorf("%s was not replaced by the imported record", id)
+				}
+			}
+			sort.Strings(ids)
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("records = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -19,14 +19,41 @@ import warnings
 # Suppress all warnings to ensure clean JSON output
 warnings.filterwarnings("ignore")
 
+class VideoRejectedError(Exception):
+    """Raised when a video is refused before processing; reason is a short machine-readable code"""
+    reason = "rejected"
+    def __init__(self, message, duration):
+        super().__init__(message)
+        self.duration = duration
+
+class VideoTooLongError(VideoRejectedError):
+    """Raised when a video exceeds the configured maximum duration"""
+    reason = "too_long"
+    def __init__(self, duration, max_duration):
+        super().__init__(f"Video duration {duration:.2f}s exceeds the maximum of {max_duration:.2f}s", duration)
+
+class SegmentOutOfRangeError(VideoRejectedError):
+    """Raised when the requested segment lies outside the video"""
+    reason = "segment_out_of_range"
+    def __init__(self, start, end, duration):
+        super().__init__(f"Segment {start:.2f}s-{end:.2f}s is outside the video duration of {duration:.2f}s", duration)
+
 class FaceProcessor:
-    def __init__(self, video_path, video_id=None, fps=1, threshold=0.6):
+    def __init__(self, video_path, video_id=None, fps=1, threshold=0.6, max_duration=0, padding=0.0, min_face_area=0, start_seconds=0.0, end_seconds=0.0):
         self.video_path = video_path
+        self.start_seconds = start_seconds
+        self.end_seconds = end_seconds
         self.fps = fps
         self.threshold = threshold
+        self.max_duration = max_duration
+        self.padding = padding
+        self.min_face_area = min_face_area
+        self.face_boxes = []
+        self.duration = 0
         self.known_faces = []
         self.known_encodings = []
         self.face_count = 0
+        self.rotation = 0
         
         # Create faces directory if it doesn't exist
         faces_dir = Path("../storage/faces")
@@ -45,34 +72,65 @@ class FaceProcessor:
         if not cap.isOpened():
             raise ValueError("Could not open video file")
             
+        # Phone footage often stores a display rotation instead of rotated
+        # pixels; record it and have OpenCV return upright frames
+        cap.set(cv2.CAP_PROP_ORIENTATION_AUTO, 1)
+        self.rotation = int(cap.get(cv2.CAP_PROP_ORIENTATION_META)) % 360
+        
         # Get video properties
         total_frames = int(cap.get(cv2.CAP_PROP_FRAME_COUNT))
         video_fps = cap.get(cv2.CAP_PROP_FPS)
         duration = total_frames / video_fps
+        self.duration = duration
         
-        print(f"Video info: {total_frames} frames, {video_fps:.2f} fps, {duration:.2f}s duration")
+        print(f"Video info: {total_frames} frames, {video_fps:.2f} fps, {duration:.2f}s duration, {self.rotation} degrees rotation")
+        
+        # Only the requested segment is analyzed; no end means the end of the video
+        end_seconds = self.end_seconds if self.end_seconds > 0 else duration
+        if self.start_seconds >= duration or end_seconds > duration:
+            cap.release()
+            raise SegmentOutOfRangeError(self.start_seconds, end_seconds, duration)
+        
+        # Reject overly long videos before spending time on frame extraction
+        if self.max_duration > 0 and end_seconds - self.start_seconds > self.max_duration:
+            cap.release()
+            raise VideoTooLongError(end_seconds - self.start_seconds, self.max_duration)
         
         frames = []
-        frame_interval = int(video_fps / self.fps)
+        frame_interval = max(1, int(video_fps / self.fps))
+        
+        # Seek to the sample at or after the segment start. Samples are numbered
+        # from the start of the video so results stay on the original timeline.
+        start_frame = int(self.start_seconds * video_fps)
+        start_frame += (frame_interval - start_frame % frame_interval) % frame_interval
+        end_frame = int(end_seconds * video_fps)
+        if start_frame > 0:
+            cap.set(cv2.CAP_PROP_POS_FRAMES, start_frame)
         
-        frame_count = 0
-        while True:
+        frame_count = start_frame
+        while frame_count <= end_frame:
             ret, frame = cap.read()
             if not ret:
                 break
                 
             if frame_count % frame_interval == 0:
+                # Record when the sample was shown; at fractional frame rates
+                # sample N drifts away from N-1 seconds as the video goes on
+                timestamp = cap.get(cv2.CAP_PROP_POS_MSEC) / 1000.0
+                if timestamp <= 0 and frame_count > 0:
+                    timestamp = frame_count / video_fps
+                
                 # Convert BGR to RGB
                 rgb_frame = cv2.cvtColor(frame, cv2.COLOR_BGR2RGB)
-                frames.append(rgb_frame)
+                frames.append((frame_count // frame_interval + 1, round(timestamp, 3), rgb_frame))
                 
             frame_count += 1
             
         cap.release()
-        print(f"Extracted {len(frames)} frames at {self.fps} fps")
+        print(f"Extracted {len(frames)} frames at {self.fps} fps from {self.start_seconds:.2f}s to {end_seconds:.2f}s")
         return frames
         
-    def process_faces(self, frame, frame_num):
+    def process_faces(self, frame, frame_num, timestamp):
         """Process faces in a single frame"""
         # Find face locations
         face_locations = face_recognition.face_locations(frame)
@@ -83,26 +141,32 @@ class FaceProcessor:
         new_faces = []
         
         for i, (face_location, face_encoding) in enumerate(zip(face_locations, face_encodings)):
+            # Skip distant faces whose crops are too small to be useful
+            top, right, bottom, left = face_location
+            if (bottom - top) * (right - left) < self.min_face_area:
+                print(f"Face too small ({right - left}x{bottom - top}px, skipping)")
+                continue
+            
+            quality = self.face_quality(frame[top:bottom, left:right])
+            
             # Check if this face is similar to any known face
             if len(self.known_encodings) > 0:
-                matches = face_recognition.compare_faces(self.known_encodings, face_encoding, tolerance=self.threshold)
-                if any(matches):
-                    print("Duplicate face detected (skipping)")
+                distances = face_recognition.face_distance(self.known_encodings, face_encoding)
+                best_match = int(np.argmin(distances))
+                if distances[best_match] <= self.threshold:
+                    # Keep whichever sighting of the person is the better crop
+                    if quality > self.face_boxes[best_match]["quality"]:
+                        print(f"Duplicate face detected with better quality {quality:.3f} (replacing)")
+                        self.save_face(frame, frame_num, timestamp, face_location, quality, best_match)
+                    else:
+                        print("Duplicate face detected (skipping)")
                     continue
             
             # This is a new face
             self.face_count += 1
             print(f"New face detected! Face #{self.face_count}")
             
-            # Save the face image with unique filename
-            top, right, bottom, left = face_location
-            face_image = frame[top:bottom, left:right]
-            
-            # Convert to PIL Image and save with unique name
-            pil_image = Image.fromarray(face_image)
-            face_filename = f"{self.video_id}_face_{self.face_count-1:03d}.jpg"
-            face_path = Path("../storage/faces") / face_filename
-            pil_image.save(face_path, "JPEG", quality=95)
+            face_filename = self.save_face(frame, frame_num, timestamp, face_location, quality, self.face_count - 1)
             
             # Add to known faces
             self.known_faces.append(face_filename)
@@ -111,6 +175,57 @@ class FaceProcessor:
             
         return new_faces
         
+    def save_face(self, frame, frame_num, timestamp, face_location, quality, index):
+        """Save the padded crop of a face as face number index and record its box"""
+        top, right, bottom, left = face_location
+        padded_top, padded_right, padded_bottom, padded_left = self.pad_box(face_location, frame.shape)
+        face_image = frame[padded_top:padded_bottom, padded_left:padded_right]
+        
+        # Convert to PIL Image and save with unique name
+        pil_image = Image.fromarray(face_image)
+        face_filename = f"{self.video_id}_face_{index:03d}.jpg"
+        face_path = Path("../storage/faces") / face_filename
+        pil_image.save(face_path, "JPEG", quality=95)
+        
+        # Keep the tight box for overlays and the padded box of the saved crop
+        face_box = {
+            "face": f"faces/{face_filename}",
+            "frame": frame_num,
+            "timestamp_seconds": timestamp,
+            "box": {"top": top, "right": right, "bottom": bottom, "left": left},
+            "padded_box": {"top": padded_top, "right": padded_right, "bottom": padded_bottom, "left": padded_left},
+            "quality": quality
+        }
+        if index < len(self.face_boxes):
+            self.face_boxes[index] = face_box
+        else:
+            self.face_boxes.append(face_box)
+        return face_filename
+        
+    def face_quality(self, face_image):
+        """Score a tight face crop from 0 to 1 by its sharpness and brightness"""
+        if face_image.size == 0:
+            return 0.0
+        gray = cv2.cvtColor(face_image, cv2.COLOR_RGB2GRAY)
+        # Variance of the Laplacian drops sharply for blurred images
+        sharpness = min(1.0, cv2.Laplacian(gray, cv2.CV_64F).var() / 100.0)
+        # Faces that are too dark or washed out score lower
+        brightness = 1.0 - abs(float(gray.mean()) - 128.0) / 128.0
+        return round(0.7 * sharpness + 0.3 * brightness, 3)
+        
+    def pad_box(self, face_location, frame_shape):
+        """Grow a (top, right, bottom, left) box by the padding factor on each side, clamped to the frame"""
+        top, right, bottom, left = face_location
+        pad_y = int((bottom - top) * self.padding)
+        pad_x = int((right - left) * self.padding)
+        height, width = frame_shape[:2]
+        return (
+            max(0, top - pad_y),
+            min(width, right + pad_x),
+            min(height, bottom + pad_y),
+            max(0, left - pad_x)
+        )
+        
     def process_video(self):
         """Process the entire video"""
         start_time = time.time()
@@ -119,9 +234,9 @@ class FaceProcessor:
         frames = self.extract_frames(self.video_path)
         
         # Process each frame
-        for i, frame in enumerate(frames):
-            print(f"Processing frame {i+1}/{len(frames)}")
-            self.process_faces(frame, i+1)
+        for i, (frame_num, timestamp, frame) in enumerate(frames):
+            print(f"Processing frame {i+1}/{len(frames)} (sample {frame_num} at {timestamp:.3f}s)")
+            self.process_faces(frame, frame_num, timestamp)
             
         processing_time = time.time() - start_time
         print(f"Processing complete! Found {self.face_count} unique faces in {processing_time:.2f} seconds")
@@ -129,6 +244,9 @@ class FaceProcessor:
         return {
             "unique_faces_count": self.face_count,
             "faces": [f"faces/{face}" for face in self.known_faces],
+            "face_boxes": self.face_boxes,
+            "rotation": self.rotation,
+            "duration_seconds": self.duration,
             "message": f"Successfully processed video. Found {self.face_count} unique faces.",
             "processing_time_seconds": processing_time
         }
@@ -139,6 +257,12 @@ def main():
     parser.add_argument("--video-id", help="Unique video ID for face naming")
     parser.add_argument("--fps", type=int, default=1, help="Frames per second to extract (default: 1)")
     parser.add_argument("--threshold", type=float, default=0.6, help="Face similarity threshold (default: 0.6)")
+    parser.add_argument("--padding", type=float, default=0.0, help="Face crop padding as a fraction of the face size on each side (default: 0.0)")
+    parser.add_argument("--min-face-area", type=int, default=0, help="Minimum face bounding box area in pixels (default: 0, no minimum)")
+    parser.add_argument("--model-version", default="", help="Face model version recorded with the results")
+    parser.add_argument("--max-duration", type=float, default=0, help="Maximum video duration in seconds (default: 0, no limit)")
+    parser.add_argument("--start-seconds", type=float, default=0, help="Start of the segment to analyze in seconds (default: 0)")
+    parser.add_argument("--end-seconds", type=float, default=0, help="End of the segment to analyze in seconds (default: 0, the end of the video)")
     
     args = parser.parse_args()
     
@@ -147,13 +271,30 @@ def main():
         sys.exit(1)
         
     try:
-        processor = FaceProcessor(args.video_path, args.video_id, args.fps, args.threshold)
+        processor = FaceProcessor(args.video_path, args.video_id, args.fps, args.threshold, args.max_duration, args.padding, args.min_face_area, args.start_seconds, args.end_seconds)
         result = processor.process_video()
+        result["model_version"] = args.model_version
         
         sys.stdout.flush()  # Clear any buffered output
         print(json.dumps(result, indent=2))
         sys.stdout.flush()  # Ensure output is sent
         
+    except VideoRejectedError as e:
+        rejected_response = {
+            "error": str(e),
+            "rejected": True,
+            "reason": e.reason,
+            "duration_seconds": e.duration,
+            "unique_faces_count": 0,
+            "faces": [],
+            "message": "Video rejected",
+            "processing_time_seconds": 0
+        }
+        sys.stdout.flush()  # Clear any buffered output
+        print(json.dumps(rejected_response, indent=2))
+        sys.stdout.flush()  # Ensure output is sent
+        sys.exit(2)
+        
     except Exception as e:
         error_response = {
             "error": f"Processing failed: {str(e)}",
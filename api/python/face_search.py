@@ -36,9 +36,26 @@ def load_and_encode_image(image_path):
         print(f"Error loading image {image_path}: {str(e)}")
         return None
 
-def compare_faces(search_encoding, face_images, similarity_threshold=0.5):
+# Supported ways of comparing face encodings
+DISTANCE_METRICS = ("euclidean", "cosine")
+
+# Default similarity thresholds per metric. For the unit-length encodings dlib
+# produces, the cosine default accepts the same faces as the euclidean one.
+DEFAULT_THRESHOLDS = {"euclidean": 0.5, "cosine": 0.875}
+
+def face_similarity(stored_encoding, search_encoding, metric):
+    """Similarity of two face encodings under the given metric, 1 for identical faces"""
+    if metric == "cosine":
+        norms = np.linalg.norm(stored_encoding) * np.linalg.norm(search_encoding)
+        if norms == 0:
+            return 0.0
+        return float(np.dot(stored_encoding, search_encoding) / norms)
+    return float(1 - face_recognition.face_distance([stored_encoding], search_encoding)[0])
+
+def compare_faces(search_encoding, face_images, similarity_threshold=0.5, metric="euclidean"):
     """Compare search face with stored face images"""
     matched_faces = []
+    match_scores = []
     
     for face_image in face_images:
         try:
@@ -59,27 +76,30 @@ def compare_faces(search_encoding, face_images, similarity_threshold=0.5):
                 continue
             
             # Compare faces
-            distance = face_recognition.face_distance([stored_encoding], search_encoding)[0]
-            similarity = 1 - distance
+            similarity = face_similarity(stored_encoding, search_encoding, metric)
             
             # If similarity is above threshold, consider it a match
             if similarity >= similarity_threshold:
                 matched_faces.append(face_image)  # Keep original path for response
+                match_scores.append({"face": face_image, "similarity": float(similarity)})
                 print(f"Match found: {face_image} (similarity: {similarity:.3f})")
             
         except Exception as e:
             print(f"Error comparing with {face_image}: {str(e)}")
             continue
     
-    return matched_faces
+    return matched_faces, match_scores
 
 def main():
     parser = argparse.ArgumentParser(description="Search for faces in stored images")
     parser.add_argument("search_image", help="Path to the search image")
     parser.add_argument("--face-images", help="Comma-separated list of face images to compare")
-    parser.add_argument("--threshold", type=float, default=0.5, help="Similarity threshold (default: 0.5)")
+    parser.add_argument("--threshold", type=float, default=None, help="Similarity threshold (default: 0.5 for euclidean, 0.875 for cosine)")
+    parser.add_argument("--distance-metric", choices=DISTANCE_METRICS, default="euclidean", help="How face encodings are compared (default: euclidean)")
+    parser.add_argument("--model-version", default="", help="Face model version recorded with the results")
     
     args = parser.parse_args()
+    threshold = args.threshold if args.threshold is not None else DEFAULT_THRESHOLDS[args.distance_metric]
     
     if not os.path.exists(args.search_image):
         print(json.dumps({"error": "Search image not found"}))
@@ -106,13 +126,16 @@ def main():
             sys.exit(1)
         
         # Compare faces
-        matched_faces = compare_faces(search_encoding, face_images, args.threshold)
+        matched_faces, match_scores = compare_faces(search_encoding, face_images, threshold, args.distance_metric)
         
         # Prepare result
         result = {
             "matched_faces": matched_faces,
+            "match_scores": match_scores,
             "total_faces_checked": len(face_images),
-            "matches_found": len(matched_faces)
+            "matches_found": len(matched_faces),
+            "model_version": args.model_version,
+            "distance_metric": args.distance_metric
         }
         
         sys.stdout.flush()  # Clear any buffered output
@@ -123,6 +146,7 @@ def main():
         error_response = {
             "error": f"Face search failed: {str(e)}",
             "matched_faces": [],
+            "match_scores": [],
             "total_faces_checked": 0,
             "matches_found": 0
         }
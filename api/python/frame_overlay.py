@@ -0,0 +1,93 @@
+#!/usr/bin/env python3
+"""
+Frame Overlay Renderer
+Extracts the frame at a timestamp and draws face bounding boxes on it
+"""
+
+import sys
+import json
+import os
+import argparse
+import cv2
+import warnings
+
+# Suppress all warnings to ensure clean JSON output
+warnings.filterwarnings("ignore")
+
+BOX_COLOR = (0, 255, 0)
+LABEL_COLOR = (0, 0, 0)
+
+def extract_frame(video_path, timestamp):
+    """Return the upright BGR frame at timestamp seconds"""
+    cap = cv2.VideoCapture(video_path)
+    if not cap.isOpened():
+        raise ValueError("Could not open video file")
+    
+    # Boxes were detected on upright frames, so rotate the same way
+    cap.set(cv2.CAP_PROP_ORIENTATION_AUTO, 1)
+    cap.set(cv2.CAP_PROP_POS_MSEC, timestamp * 1000)
+    ret, frame = cap.read()
+    cap.release()
+    
+    if not ret:
+        raise ValueError(f"No frame at {timestamp:.2f}s")
+    return frame
+
+def draw_boxes(frame, boxes):
+    """Draw each box with its label above it"""
+    for box in boxes:
+        top, right, bottom, left = box["top"], box["right"], box["bottom"], box["left"]
+        cv2.rectangle(frame, (left, top), (right, bottom), BOX_COLOR, 2)
+        
+        label = box.get("label", "")
+        if label:
+            (text_width, text_height), baseline = cv2.getTextSize(label, cv2.FONT_HERSHEY_SIMPLEX, 0.5, 1)
+            label_top = max(0, top - text_height - baseline - 4)
+            cv2.rectangle(frame, (left, label_top), (left + text_width + 4, label_top + text_height + baseline + 4), BOX_COLOR, -1)
+            cv2.putText(frame, label, (left + 2, label_top + text_height + 2), cv2.FONT_HERSHEY_SIMPLEX, 0.5, LABEL_COLOR, 1)
+
+def main():
+    parser = argparse.ArgumentParser(description="Draw face boxes on a video frame")
+    parser.add_argument("video_path", help="Path to the video file")
+    parser.add_argument("--time", type=float, required=True, help="Timestamp of the frame in seconds")
+    parser.add_argument("--boxes", default="[]", help="JSON list of boxes with top, right, bottom, left and an optional label")
+    parser.add_argument("--output", required=True, help="Path of the JPEG image to write")
+    
+    args = parser.parse_args()
+    
+    if not os.path.exists(args.video_path):
+        print(json.dumps({"error": "Video file not found"}))
+        sys.exit(1)
+        
+    try:
+        boxes = json.loads(args.boxes)
+        frame = extract_frame(args.video_path, args.time)
+        draw_boxes(frame, boxes)
+        
+        if not cv2.imwrite(args.output, frame, [cv2.IMWRITE_JPEG_QUALITY, 90]):
+            raise ValueError("Could not write overlay image")
+        
+        height, width = frame.shape[:2]
+        result = {
+            "output": args.output,
+            "width": width,
+            "height": height,
+            "boxes_drawn": len(boxes)
+        }
+        
+        sys.stdout.flush()  # Clear any buffered output
+        print(json.dumps(result, indent=2))
+        sys.stdout.flush()  # Ensure output is sent
+        
+    except Exception as e:
+        error_response = {
+            "error": f"Overlay failed: {str(e)}",
+            "boxes_drawn": 0
+        }
+        sys.stdout.flush()  # Clear any buffered output
+        print(json.dumps(error_response, indent=2))
+        sys.stdout.flush()  # Ensure output is sent
+        sys.exit(1)
+
+if __name__ == "__main__":
+    main()